@@ -18,15 +18,23 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/ericcornelissen/ghasum/internal/cache"
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+	"github.com/ericcornelissen/ghasum/internal/github"
 )
 
 func cmdCache(argv []string) error {
 	var (
-		flags     = flag.NewFlagSet(cmdNameCache, flag.ContinueOnError)
-		flagCache = flags.String(flagNameCache, "", "")
+		flags        = flag.NewFlagSet(cmdNameCache, flag.ContinueOnError)
+		flagCache    = flags.String(flagNameCache, "", "")
+		flagMaxFiles = flags.Int(flagNameMaxFiles, github.DefaultLimits.Files, "")
+		flagMaxSize  = flags.String(flagNameMaxSize, "", "")
+		flagSumfile  = flags.String(flagNameSumfile, "", "")
 	)
 
 	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
@@ -37,24 +45,48 @@ func cmdCache(argv []string) error {
 	args := flags.Args()
 	if len(args) < 1 {
 		return errUsage
-	} else if len(args) > 1 {
-		return errors.New("only one command can be run at the time")
 	}
 
-	c, err := cache.New(*flagCache, false)
+	command, args := args[0], args[1:]
+	switch command {
+	case "export", "import":
+		if len(args) < 1 || len(args) > 2 {
+			return errUsage
+		}
+	case "serve":
+		if len(args) > 1 {
+			return errUsage
+		}
+	default:
+		if len(args) > 0 {
+			return errors.New("only one command can be run at the time")
+		}
+	}
+
+	limits, err := getLimits("", *flagMaxFiles, *flagMaxSize)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	c, err := cache.New(*flagCache)
 	if err != nil {
 		return errors.Join(errUnexpected, err)
 	}
 
 	msg := "Ok"
-	command := args[0]
 	switch command {
 	case "clear":
 		err = c.Clear()
 	case "evict":
-		err = c.Evict()
+		err = evict(c)
+	case "export":
+		err = cacheExport(c, args, *flagSumfile)
+	case "import":
+		err = cacheImport(c, args, *flagSumfile, limits)
 	case "path":
 		msg = c.Path()
+	case "serve":
+		err = cacheServe(c, args, limits)
 	default:
 		return fmt.Errorf(`unknown command %q (see "ghasum help cache")`, command)
 	}
@@ -67,22 +99,148 @@ func cmdCache(argv []string) error {
 	return nil
 }
 
+func cacheExport(c cache.Cache, args []string, sumfile string) error {
+	file := args[0]
+	target, err := getTarget(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := c.Init(); err != nil {
+		return fmt.Errorf("could not initialize cache: %v", err)
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("could not create %q: %v", file, err)
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: sumfile,
+		Cache:   c,
+	}
+
+	if err := ghasum.ExportCache(&cfg, out); err != nil {
+		_ = out.Close()
+		_ = os.Remove(file)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("could not write %q: %v", file, err)
+	}
+
+	return nil
+}
+
+func cacheImport(c cache.Cache, args []string, sumfile string, limits github.Limits) error {
+	file := args[0]
+	target, err := getTarget(args[1:])
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("could not open %q: %v", file, err)
+	}
+
+	defer in.Close()
+
+	if err := c.Init(); err != nil {
+		return fmt.Errorf("could not initialize cache: %v", err)
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: sumfile,
+		Cache:   c,
+		Limits:  limits,
+	}
+
+	return ghasum.ImportCache(&cfg, in)
+}
+
+func cacheServe(c *cache.Dir, args []string, limits github.Limits) error {
+	addr := "localhost:8080"
+	if len(args) > 0 {
+		addr = args[0]
+	}
+
+	if err := c.Init(); err != nil {
+		return fmt.Errorf("could not initialize cache: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %q: %v", addr, err)
+	}
+
+	fmt.Printf("Serving cache on http://%s\n", listener.Addr())
+
+	server := http.Server{
+		Handler:           cache.Handler(c, limits),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return server.Serve(listener)
+}
+
 func helpCache() string {
-	return `usage: ghasum cache [flags] <command>
+	return `usage: ghasum cache [flags] <command> [arguments]
 
 Utilities for managing the ghasum cache. This cache is where ghasum stores and
 looks up repositories it needs to do its job. The maximum age of entries in the
-cache is 5 days, after which it will be evicted.
+cache is 5 days, after which it will be evicted. Directories in the cache that
+are writable by other users are made private, and if any directory is owned by
+another user the cache is rejected.
 
 The available commands are:
 
-    clear   Remove all data from the cache.
-    evict   Remove old data from the cache.
-    path    Show the path to the cache.
+    clear                  Remove all data from the cache.
+    evict                  Remove old data from the cache.
+    export file [target]   Write the cache entries needed to verify the target
+                           to an archive. If no target is provided it will
+                           default to the current working directory.
+    import file [target]   Add the cache entries in an archive to the cache.
+                           Every entry is checked against the target's gha.sum
+                           file and nothing is added if any entry mismatches. If
+                           no target is provided it will default to the current
+                           working directory.
+    path                   Show the path to the cache.
+    serve [address]        Serve the cache over HTTP at the address so that it
+                           can be used as a remote cache. If no address is
+                           provided it will default to localhost:8080.
 
 The available flags are:
 
     -cache dir
         The location of the cache directory. Defaults to a directory named
-        .ghasum/ in the user's home directory.`
+        ghasum/ in $XDG_CACHE_HOME if it is set, or a directory named .ghasum/
+        in the user's home directory otherwise.
+    -max-files count
+        The maximum number of files in an archive, used by the import and serve
+        commands. Use 0 for no limit.
+        Defaults to 100000.
+    -max-size size
+        The maximum total size of the files in an archive, used by the import
+        and serve commands, in bytes or with a K, M, or G suffix. Use 0 for no
+        limit.
+        Defaults to 1G.
+    -sumfile path
+        The path of the gha.sum file relative to the target, used by the export
+        and import commands.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.
+
+A remote cache is a cache shared over HTTP, for example between CI runners, that
+is used with the -remote-cache flag of other commands. An entry for a repository
+at a ref is located at <url>/github.com/<owner>/<project>/<ref>, retrieved with a
+GET request and stored with a PUT request. The content of a remote cache is not
+trusted, checksums are always computed locally and a downloaded repository is
+only added to the local cache if it matches its checksum in the gha.sum file.
+Requests are not authenticated, so only serve a cache on a trusted network.`
 }
@@ -16,9 +16,147 @@ package main
 
 import (
 	"errors"
+	"flag"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ericcornelissen/ghasum/internal/cache"
+	"github.com/ericcornelissen/ghasum/internal/github"
+	"github.com/ericcornelissen/ghasum/internal/sshsig"
 )
 
+// limitFlags are the flags that limit the resources used to fetch and hash a
+// single Action.
+type limitFlags struct {
+	download *string
+	files    *int
+	size     *string
+}
+
+// helpLimitFlags is the help text for the flags defined by addLimitFlags.
+const helpLimitFlags = `    -max-download size
+        The maximum size of the git objects fetched for a single Action, in
+        bytes or with a K, M, or G suffix. Use 0 for no limit.
+        Defaults to 1G.
+    -max-files count
+        The maximum number of files in the repository of a single Action. Use 0
+        for no limit.
+        Defaults to 100000.
+    -max-size size
+        The maximum total size of the files in the repository of a single
+        Action, in bytes or with a K, M, or G suffix. Use 0 for no limit.
+        Defaults to 1G.`
+
+// addLimitFlags defines the flags that limit the resources used to fetch and
+// hash a single Action on the given flag set.
+func addLimitFlags(flags *flag.FlagSet) limitFlags {
+	return limitFlags{
+		download: flags.String(flagNameMaxDownload, "", ""),
+		files:    flags.Int(flagNameMaxFiles, github.DefaultLimits.Files, ""),
+		size:     flags.String(flagNameMaxSize, "", ""),
+	}
+}
+
+// limits returns the limits set with the flags.
+func (f limitFlags) limits() (github.Limits, error) {
+	return getLimits(*f.download, *f.files, *f.size)
+}
+
+// evict removes old entries from the given cache as well as any leftover
+// temporary caches (see cache.EvictTemporary), also if the cache is in memory.
+func evict(c cache.Cache) error {
+	cache.EvictTemporary()
+	return c.Evict()
+}
+
+func getCache(location string, ephemeral bool, remote string, pull bool, limits github.Limits) (cache.Cache, error) {
+	var c cache.Cache
+	if ephemeral {
+		c = cache.NewMemory()
+	} else {
+		dir, err := cache.New(location)
+		if err != nil {
+			return nil, err
+		}
+
+		c = dir
+	}
+
+	if remote == "" {
+		return c, nil
+	}
+
+	return cache.NewRemote(c, remote, pull, limits)
+}
+
+func getJobs(n int) (int, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("invalid number of jobs %d", n)
+	}
+
+	return n, nil
+}
+
+func getLimits(download string, files int, size string) (github.Limits, error) {
+	limits := github.DefaultLimits
+	limits.Files = files
+
+	if download != "" {
+		n, err := parseSize(download)
+		if err != nil {
+			return limits, fmt.Errorf("invalid download limit %q", download)
+		}
+
+		limits.Download = n
+	}
+
+	if size != "" {
+		n, err := parseSize(size)
+		if err != nil {
+			return limits, fmt.Errorf("invalid size limit %q", size)
+		}
+
+		limits.Size = n
+	}
+
+	return limits, nil
+}
+
+func getSigner(file string) (ssh.Signer, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the key: %v", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(raw)
+	if errors.As(err, new(*ssh.PassphraseMissingError)) {
+		return nil, errors.New("keys protected by a passphrase are not supported")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not parse the key: %v", err)
+	}
+
+	return signer, nil
+}
+
+func getSigners(file string) ([]sshsig.AllowedSigner, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the allowed signers: %v", err)
+	}
+
+	signers, err := sshsig.ParseAllowedSigners(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the allowed signers: %v", err)
+	}
+
+	return signers, nil
+}
+
 func getTarget(args []string) (string, error) {
 	if len(args) == 0 {
 		wd, err := os.Getwd()
@@ -31,3 +169,26 @@ func getTarget(args []string) (string, error) {
 		return args[0], nil
 	}
 }
+
+func parseSize(s string) (int64, error) {
+	units := map[string]int64{
+		"K": 1 << 10,
+		"M": 1 << 20,
+		"G": 1 << 30,
+	}
+
+	multiplier := int64(1)
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			s, multiplier = strings.TrimSuffix(s, suffix), unit
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, errors.New("invalid size")
+	}
+
+	return n * multiplier, nil
+}
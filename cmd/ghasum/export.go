@@ -0,0 +1,134 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+	"github.com/ericcornelissen/ghasum/internal/sbom"
+)
+
+func cmdExport(argv []string) error {
+	var (
+		flags       = flag.NewFlagSet(cmdNameExport, flag.ContinueOnError)
+		flagFormat  = flags.String(flagNameFormat, sbom.CycloneDX.String(), "")
+		flagSumfile = flags.String(flagNameSumfile, "", "")
+	)
+
+	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
+	if err := flags.Parse(argv); err != nil {
+		return errUsage
+	}
+
+	args := flags.Args()
+	if len(args) > 1 {
+		return errUsage
+	}
+
+	format, err := sbom.ParseFormat(*flagFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return errUsage
+	}
+
+	target, err := getTarget(args)
+	if err != nil {
+		return err
+	}
+
+	created, err := getCreated()
+	if err != nil {
+		return err
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: *flagSumfile,
+	}
+
+	components, err := ghasum.Components(&cfg)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	name, err := filepath.Abs(target)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	doc := sbom.Document{
+		Name:        filepath.Base(name),
+		Tool:        "ghasum",
+		ToolVersion: version,
+		Created:     created,
+		Components:  components,
+	}
+
+	if err := sbom.Encode(os.Stdout, &doc, format); err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	return nil
+}
+
+// getCreated returns the creation time for exported documents, which is taken
+// from SOURCE_DATE_EPOCH for reproducibility if it is set.
+func getCreated() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+func helpExport() string {
+	return `usage: ghasum export [flags] [target]
+
+Export the checksums for the target as a software bill of materials (SBOM). If
+no target is provided it will default to the current working directory. If
+ghasum is not yet initialized this command errors (see "ghasum help init").
+
+Every GitHub Action in the gha.sum file is exported as a component identified
+by a package URL of the form pkg:github/owner/repo@ref, together with its
+checksum and the jobs, in the form workflow:job, in which it is used. The SBOM
+is written to stdout.
+
+If SOURCE_DATE_EPOCH is set it is used as the creation time of the SBOM instead
+of the current time, for reproducible output.
+
+The available flags are:
+
+    -format format
+        The format of the SBOM, one of: cyclonedx, spdx.
+        Defaults to cyclonedx.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
+}
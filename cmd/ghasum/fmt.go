@@ -0,0 +1,99 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+)
+
+func cmdFmt(argv []string) error {
+	var (
+		flags       = flag.NewFlagSet(cmdNameFmt, flag.ContinueOnError)
+		flagCheck   = flags.Bool(flagNameCheck, false, "")
+		flagSumfile = flags.String(flagNameSumfile, "", "")
+	)
+
+	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
+	if err := flags.Parse(argv); err != nil {
+		return errUsage
+	}
+
+	args := flags.Args()
+	if len(args) > 1 {
+		return errUsage
+	}
+
+	target, err := getTarget(args)
+	if err != nil {
+		return err
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: *flagSumfile,
+	}
+
+	changed, err := ghasum.Format(&cfg, *flagCheck)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	if cnt := len(changed); cnt > 0 && *flagCheck {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%d checksum file(s) not formatted:\n", cnt))
+		for _, name := range changed {
+			sb.WriteString(fmt.Sprintf("  %s\n", name))
+		}
+
+		return errors.Join(errFailure, errors.New(sb.String()))
+	}
+
+	fmt.Println("Ok")
+	return nil
+}
+
+func helpFmt() string {
+	return `usage: ghasum fmt [flags] [target]
+
+Format the checksums for the target. If no target is provided it will default to
+the current working directory. If ghasum is not yet initialized this command
+errors (see "ghasum help init").
+
+The gha.sum file is rewritten in the form written by ghasum, with sorted entries
+and headers, LF line endings, and without trailing whitespace. The checksums
+themselves are not changed and not verified. If the file cannot be parsed this
+command errors. A rewritten file loses its inline signature, if any, and must be
+signed again (see "ghasum help sign").
+
+If the gha.sum file uses the split layout (see "ghasum help init") the checksum
+file of every workflow is formatted as well.
+
+The available flags are:
+
+    -check
+        Only check whether the checksum files are formatted, exiting with a
+        non-zero exit code if any is not.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
+}
@@ -56,11 +56,16 @@ for the GitHub Actions used in a project to avoid using Actions that changed.
 
 The available commands are:
 
-    cache     Manage the ghasum cache.
-    init      Initialize ghasum for a repository.
-    update    Update the checksums for a repository.
-    verify    Verify the checksums for a repository.
-    version   Print the ghasum version.
+    cache          Manage the ghasum cache.
+    export         Export the checksums as a software bill of materials.
+    fmt            Format the checksums for a repository.
+    init           Initialize ghasum for a repository.
+    merge-driver   Merge checksums as a git merge driver.
+    migrate        Migrate the checksums for a repository.
+    sign           Sign the checksums for a repository.
+    update         Update the checksums for a repository.
+    verify         Verify the checksums for a repository.
+    version        Print the ghasum version.
 
 Use "ghasum help <command>" for more information about a command.`
 }
@@ -19,16 +19,24 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
 
-	"github.com/ericcornelissen/ghasum/internal/cache"
+	"github.com/ericcornelissen/ghasum/internal/checksum"
 	"github.com/ericcornelissen/ghasum/internal/ghasum"
 )
 
 func cmdInit(argv []string) error {
 	var (
-		flags       = flag.NewFlagSet(cmdNameInit, flag.ContinueOnError)
-		flagCache   = flags.String(flagNameCache, "", "")
-		flagNoCache = flags.Bool(flagNameNoCache, false, "")
+		flags        = flag.NewFlagSet(cmdNameInit, flag.ContinueOnError)
+		flagAlgo     = flags.String(flagNameAlgo, checksum.BestAlgo.String(), "")
+		flagCache    = flags.String(flagNameCache, "", "")
+		flagJobs     = flags.Int(flagNameJobs, runtime.NumCPU(), "")
+		flagLimits   = addLimitFlags(flags)
+		flagManifest = flags.Bool(flagNameManifest, false, "")
+		flagNoCache  = flags.Bool(flagNameNoCache, false, "")
+		flagRemote   = flags.String(flagNameRemote, "", "")
+		flagSplit    = flags.Bool(flagNameSplit, false, "")
+		flagSumfile  = flags.String(flagNameSumfile, "", "")
 	)
 
 	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
@@ -46,15 +54,37 @@ func cmdInit(argv []string) error {
 		return err
 	}
 
-	c, err := cache.New(*flagCache, *flagNoCache)
+	algo, err := checksum.ParseName(*flagAlgo)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	limits, err := flagLimits.limits()
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	jobs, err := getJobs(*flagJobs)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	c, err := getCache(*flagCache, *flagNoCache, *flagRemote, false, limits)
 	if err != nil {
 		return errors.Join(errCache, err)
 	}
 
 	cfg := ghasum.Config{
-		Repo:  os.DirFS(target),
-		Path:  target,
-		Cache: c,
+		Repo:      os.DirFS(target),
+		Path:      target,
+		Sumfile:   *flagSumfile,
+		Split:     *flagSplit,
+		Cache:     c,
+		Manifest:  *flagManifest,
+		Algo:      algo,
+		Limits:    limits,
+		Jobs:      jobs,
+		Generator: generator,
 	}
 
 	if err := ghasum.Initialize(&cfg); err != nil {
@@ -72,12 +102,48 @@ Initialize ghasum for the target. If no target is provided it will default to
 the current working directory. If ghasum is already initialize for the target
 this command will error.
 
+By default the checksums are stored in .github/workflows/gha.sum. To store them
+elsewhere use the -sumfile flag. Other commands find the checksums at that path
+if the default file points to it with a sumfile header, for example:
+
+    version 1
+    sumfile .github/checksums/gha.sum
+
+Otherwise the -sumfile flag must be provided to every command. To let different
+people own the checksums of different workflows use the -split flag.
+
 The available flags are:
 
+    -algo name
+        The hashing algorithm to compute new checksums with, one of sha256,
+        sha512, blake3, or sha256-modes. Only sha256-modes covers the modes of
+        files and the targets of symbolic links.
+        Defaults to sha256.
     -cache dir
         The location of the cache directory. This is where ghasum stores and
         looks up repositories it needs.
-        Defaults to a directory named .ghasum in the user's home directory.
+        Defaults to a directory named ghasum in $XDG_CACHE_HOME if it is set,
+        or a directory named .ghasum in the user's home directory otherwise.
+    -jobs count
+        The maximum number of Actions to fetch and hash at the same time.
+        Defaults to the number of CPUs.
+    -manifest
+        Store the hashes of the files covered by every checksum in the file
+        gha.sum.manifest next to the gha.sum file, which is used to explain
+        checksum mismatches. An existing manifest is always kept up-to-date.
+` + helpLimitFlags + `
     -no-cache
-        Disable the use of the cache. Makes the -cache flag ineffective.`
+        Disable the use of the cache. Makes the -cache flag ineffective.
+    -remote-cache url
+        The url of a remote cache (see "ghasum help cache"). Repositories that
+        are fetched are uploaded to it. Repositories are never downloaded from
+        it, so the checksums are always computed from repositories on GitHub.
+    -split
+        Use a separate checksum file for every workflow, named after the
+        workflow (for example ci.yml.sum) and stored next to the gha.sum file.
+        The gha.sum file records the layout and is used by other commands.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
 }
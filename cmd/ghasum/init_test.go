@@ -24,7 +24,8 @@ func TestInit(t *testing.T) {
 	t.Parallel()
 
 	params := testscript.Params{
-		Dir: "../../testdata/init",
+		Dir:   "../../testdata/init",
+		Setup: setup,
 	}
 
 	testscript.Run(t, params)
@@ -29,12 +29,17 @@ type (
 )
 
 const (
-	cmdNameCache   = "cache"
-	cmdNameHelp    = "help"
-	cmdNameInit    = "init"
-	cmdNameUpdate  = "update"
-	cmdNameVerify  = "verify"
-	cmdNameVersion = "version"
+	cmdNameCache       = "cache"
+	cmdNameExport      = "export"
+	cmdNameFmt         = "fmt"
+	cmdNameHelp        = "help"
+	cmdNameInit        = "init"
+	cmdNameMergeDriver = "merge-driver"
+	cmdNameMigrate     = "migrate"
+	cmdNameSign        = "sign"
+	cmdNameUpdate      = "update"
+	cmdNameVerify      = "verify"
+	cmdNameVersion     = "version"
 )
 
 const (
@@ -45,11 +50,29 @@ const (
 )
 
 const (
-	flagNameCache   = "cache"
-	flagNameForce   = "force"
-	flagNameNoCache = "no-cache"
-	flagNameNoEvict = "no-evict"
-	flagNameOffline = "offline"
+	flagNameAlgo           = "algo"
+	flagNameAllowedSigners = "allowed-signers"
+	flagNameCache          = "cache"
+	flagNameCheck          = "check"
+	flagNameDetached       = "detached"
+	flagNameDryRun         = "dry-run"
+	flagNameForce          = "force"
+	flagNameFormat         = "format"
+	flagNameJobs           = "jobs"
+	flagNameKey            = "key"
+	flagNameManifest       = "manifest"
+	flagNameMaxDownload    = "max-download"
+	flagNameMaxFiles       = "max-files"
+	flagNameMaxSize        = "max-size"
+	flagNameNoCache        = "no-cache"
+	flagNameNoEvict        = "no-evict"
+	flagNameOffline        = "offline"
+	flagNameRemote         = "remote-cache"
+	flagNameRequireSig     = "require-signature"
+	flagNameSplit          = "split"
+	flagNameStrict         = "strict"
+	flagNameSumfile        = "sumfile"
+	flagNameToVersion      = "to-version"
 )
 
 var (
@@ -60,21 +83,31 @@ var (
 )
 
 var commands = map[string]Command{
-	cmdNameCache:   cmdCache,
-	cmdNameHelp:    cmdHelp,
-	cmdNameInit:    cmdInit,
-	cmdNameUpdate:  cmdUpdate,
-	cmdNameVerify:  cmdVerify,
-	cmdNameVersion: cmdVersion,
+	cmdNameCache:       cmdCache,
+	cmdNameExport:      cmdExport,
+	cmdNameFmt:         cmdFmt,
+	cmdNameHelp:        cmdHelp,
+	cmdNameInit:        cmdInit,
+	cmdNameMergeDriver: cmdMergeDriver,
+	cmdNameMigrate:     cmdMigrate,
+	cmdNameSign:        cmdSign,
+	cmdNameUpdate:      cmdUpdate,
+	cmdNameVerify:      cmdVerify,
+	cmdNameVersion:     cmdVersion,
 }
 
 var helpers = map[string]Helper{
-	cmdNameCache:   helpCache,
-	cmdNameHelp:    help,
-	cmdNameInit:    helpInit,
-	cmdNameUpdate:  helpUpdate,
-	cmdNameVerify:  helpVerify,
-	cmdNameVersion: helpVersion,
+	cmdNameCache:       helpCache,
+	cmdNameExport:      helpExport,
+	cmdNameFmt:         helpFmt,
+	cmdNameHelp:        help,
+	cmdNameInit:        helpInit,
+	cmdNameMergeDriver: helpMergeDriver,
+	cmdNameMigrate:     helpMigrate,
+	cmdNameSign:        helpSign,
+	cmdNameUpdate:      helpUpdate,
+	cmdNameVerify:      helpVerify,
+	cmdNameVersion:     helpVersion,
 }
 
 func main() {
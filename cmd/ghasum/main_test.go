@@ -18,6 +18,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rogpeppe/go-internal/testscript"
 )
@@ -30,6 +31,25 @@ func TestMain(m *testing.M) {
 	os.Exit(testscript.RunMain(m, commands))
 }
 
+// age is a test script command that makes the given files a month old.
+func age(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) == 0 {
+		ts.Fatalf("usage: age file...")
+	}
+
+	old := time.Now().AddDate(0, -1, 0)
+	for _, arg := range args {
+		ts.Check(os.Chtimes(ts.MkAbs(arg), old, old))
+	}
+}
+
+// setup prepares the environment of a test script. It exposes the ghasum
+// version as $VERSION, for example for use in files compared using cmpenv.
+func setup(env *testscript.Env) error {
+	env.Setenv("VERSION", version)
+	return nil
+}
+
 func TestCli(t *testing.T) {
 	t.Parallel()
 
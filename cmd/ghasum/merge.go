@@ -0,0 +1,150 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+)
+
+// mergeDriverCommand is the command git uses to run the merge driver, with the
+// placeholders for the files to merge.
+const mergeDriverCommand = "ghasum " + cmdNameMergeDriver + " %O %A %B"
+
+func cmdMergeDriver(argv []string) error {
+	var (
+		flags       = flag.NewFlagSet(cmdNameMergeDriver, flag.ContinueOnError)
+		flagSumfile = flags.String(flagNameSumfile, "", "")
+	)
+
+	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
+	if err := flags.Parse(argv); err != nil {
+		return errUsage
+	}
+
+	args := flags.Args()
+	switch {
+	case len(args) > 0 && args[0] == "install":
+		if len(args) > 2 {
+			return errUsage
+		}
+
+		return mergeDriverInstall(args[1:], *flagSumfile)
+	case len(args) == 3:
+		return mergeDriverMerge(args[0], args[1], args[2])
+	default:
+		return errUsage
+	}
+}
+
+func mergeDriverInstall(args []string, sumfile string) error {
+	target, err := getTarget(args)
+	if err != nil {
+		return err
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: sumfile,
+	}
+
+	if err := ghasum.InstallMergeDriver(&cfg, mergeDriverCommand); err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	fmt.Println("Ok")
+	return nil
+}
+
+func mergeDriverMerge(ancestor, current, other string) error {
+	contents := make([][]byte, 3)
+	for i, file := range []string{ancestor, current, other} {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return errors.Join(errUnexpected, err)
+		}
+
+		contents[i] = raw
+	}
+
+	cfg := ghasum.Config{
+		Generator: generator,
+	}
+
+	merged, problems, err := ghasum.Merge(&cfg, contents[0], contents[1], contents[2])
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	if cnt := len(problems); cnt > 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%d conflict(s) occurred during merge:\n", cnt))
+		for _, problem := range problems {
+			sb.WriteString(fmt.Sprintf("  %s\n", problem))
+		}
+
+		return errors.Join(errFailure, errors.New(sb.String()))
+	}
+
+	if err := os.WriteFile(current, []byte(merged), 0o644); err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	return nil
+}
+
+func helpMergeDriver() string {
+	return `usage: ghasum merge-driver <ancestor> <current> <other>
+       ghasum merge-driver [flags] install [target]
+
+Merge checksum files as a git merge driver. Changes to a gha.sum file on two
+branches, for example adding checksums for different Actions, are merged at the
+level of individual checksums rather than lines. Conflicting changes, such as a
+different checksum for the same Action on both branches, are reported and this
+command errors with a non-zero exit code.
+
+The first form merges the changes from the ancestor to the other file into the
+current file, and writes the result to the current file. Git provides the files
+when it runs the merge driver. An inline signature (see "ghasum help sign") is
+removed from the result.
+
+The second form installs the merge driver for the target. If no target is
+provided it will default to the current working directory. The target must be
+the root of a git repository. The merge driver is registered in the git config
+of the repository as:
+
+    [merge "ghasum"]
+        driver = ` + mergeDriverCommand + `
+
+and assigned to the gha.sum file in the .gitattributes file of the repository,
+as well as to the checksum file of every workflow if it uses the split layout
+(see "ghasum help init"). The .gitattributes file should be committed, the git
+config must be installed in every clone. Git runs the merge driver as "ghasum",
+so it must be on the PATH.
+
+The available flags are:
+
+    -sumfile path
+        The path of the gha.sum file relative to the target, used by the second
+        form.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/checksum"
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+func cmdMigrate(argv []string) error {
+	var (
+		flags         = flag.NewFlagSet(cmdNameMigrate, flag.ContinueOnError)
+		flagAlgo      = flags.String(flagNameAlgo, checksum.BestAlgo.String(), "")
+		flagCache     = flags.String(flagNameCache, "", "")
+		flagJobs      = flags.Int(flagNameJobs, runtime.NumCPU(), "")
+		flagLimits    = addLimitFlags(flags)
+		flagNoCache   = flags.Bool(flagNameNoCache, false, "")
+		flagNoEvict   = flags.Bool(flagNameNoEvict, false, "")
+		flagOffline   = flags.Bool(flagNameOffline, false, "")
+		flagRemote    = flags.String(flagNameRemote, "", "")
+		flagSumfile   = flags.String(flagNameSumfile, "", "")
+		flagToVersion = flags.Uint(flagNameToVersion, uint(sumfile.VersionLatest), "")
+	)
+
+	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
+	if err := flags.Parse(argv); err != nil {
+		return errUsage
+	}
+
+	args := flags.Args()
+	if len(args) > 1 {
+		return errUsage
+	}
+
+	target, err := getTarget(args)
+	if err != nil {
+		return err
+	}
+
+	algo, err := checksum.ParseName(*flagAlgo)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	limits, err := flagLimits.limits()
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	jobs, err := getJobs(*flagJobs)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	c, err := getCache(*flagCache, *flagNoCache, *flagRemote, true, limits)
+	if err != nil {
+		return errors.Join(errCache, err)
+	}
+
+	if !*flagNoEvict {
+		if err := evict(c); err != nil {
+			return errors.Join(errUnexpected, err)
+		}
+	}
+
+	cfg := ghasum.Config{
+		Repo:      os.DirFS(target),
+		Path:      target,
+		Sumfile:   *flagSumfile,
+		Cache:     c,
+		Offline:   *flagOffline,
+		Limits:    limits,
+		Jobs:      jobs,
+		Generator: generator,
+	}
+
+	problems, err := ghasum.Migrate(&cfg, sumfile.Version(*flagToVersion), algo)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	if cnt := len(problems); cnt > 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%d problems(s) occurred during migration:\n", cnt))
+		for _, problem := range problems {
+			sb.WriteString(fmt.Sprintf("  %s\n", problem))
+		}
+
+		return errors.Join(errFailure, errors.New(sb.String()))
+	}
+
+	fmt.Println("Ok")
+	return nil
+}
+
+func helpMigrate() string {
+	return `usage: ghasum migrate [flags] [target]
+
+Migrate the gha.sum file for the target to another version of the file or to
+another hashing algorithm. If no target is provided it will default to the
+current working directory.
+
+Every stored checksum is verified, using the algorithm it was computed with,
+before it is migrated. If any checksum does not match this command will error
+with a non-zero exit code and the gha.sum file is not changed. If ghasum is not
+yet initialized this command errors (see "ghasum help init").
+
+The available flags are:
+
+    -algo name
+        The hashing algorithm to compute the new checksums with, one of sha256,
+        sha512, blake3, or sha256-modes. Only sha256-modes covers the modes of
+        files and the targets of symbolic links.
+        Defaults to sha256.
+    -cache dir
+        The location of the cache directory. This is where ghasum stores and
+        looks up repositories it needs.
+        Defaults to a directory named ghasum in $XDG_CACHE_HOME if it is set,
+        or a directory named .ghasum in the user's home directory otherwise.
+    -jobs count
+        The maximum number of Actions to fetch and hash at the same time.
+        Defaults to the number of CPUs.
+` + helpLimitFlags + `
+    -no-cache
+        Disable the use of the cache. Makes the -cache flag ineffective.
+    -no-evict
+        Disable cache eviction.
+    -offline
+        Run without fetching repositories from the internet, migrate exclusively
+        using the cache. If the cache is missing an entry it causes an error.
+    -remote-cache url
+        The url of a remote cache (see "ghasum help cache"). Repositories that
+        are missing from the cache are downloaded from it before they're fetched
+        and repositories that are fetched are uploaded to it. With -offline
+        repositories are still downloaded from the remote cache.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.
+    -to-version version
+        The version of the gha.sum file to migrate to.
+        Defaults to the latest version.`
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ericcornelissen/ghasum/internal/ghasum"
+)
+
+func cmdSign(argv []string) error {
+	var (
+		flags        = flag.NewFlagSet(cmdNameSign, flag.ContinueOnError)
+		flagDetached = flags.Bool(flagNameDetached, false, "")
+		flagKey      = flags.String(flagNameKey, "", "")
+		flagSumfile  = flags.String(flagNameSumfile, "", "")
+	)
+
+	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
+	if err := flags.Parse(argv); err != nil {
+		return errUsage
+	}
+
+	args := flags.Args()
+	if len(args) > 1 || *flagKey == "" {
+		return errUsage
+	}
+
+	target, err := getTarget(args)
+	if err != nil {
+		return err
+	}
+
+	signer, err := getSigner(*flagKey)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	cfg := ghasum.Config{
+		Repo:    os.DirFS(target),
+		Path:    target,
+		Sumfile: *flagSumfile,
+	}
+
+	if err := ghasum.Sign(&cfg, signer, *flagDetached); err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	fmt.Println("Ok")
+	return nil
+}
+
+func helpSign() string {
+	return `usage: ghasum sign [flags] [target]
+
+Sign the checksums for the target using an SSH key. If no target is provided it
+will default to the current working directory. The signature can be checked by
+"ghasum verify -require-signature" (see "ghasum help verify"). If ghasum is not
+yet initialized this command errors (see "ghasum help init").
+
+The signature is compatible with "ssh-keygen -Y sign" using the namespace
+"ghasum". By default it is stored inline, in a signature header of the gha.sum
+file. With -detached it is stored in a separate file next to the gha.sum file
+instead, named gha.sum.sig, in the format used by ssh-keygen. An inline
+signature is removed when the checksums are updated or migrated, after which
+the checksums must be signed again.
+
+If the gha.sum file uses the split layout (see "ghasum help init") the checksum
+file of every workflow is signed as well.
+
+The available flags are:
+
+    -detached
+        Store the signature in a separate file rather than in the gha.sum file.
+    -key file
+        The private SSH key to sign with, for example an ed25519 key created
+        with ssh-keygen. Keys protected by a passphrase are not supported.
+        Required.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
+}
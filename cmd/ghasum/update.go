@@ -19,18 +19,29 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
+	"strings"
 
 	"github.com/ericcornelissen/ghasum/internal/cache"
+	"github.com/ericcornelissen/ghasum/internal/checksum"
 	"github.com/ericcornelissen/ghasum/internal/ghasum"
 )
 
 func cmdUpdate(argv []string) error {
 	var (
-		flags       = flag.NewFlagSet(cmdNameUpdate, flag.ContinueOnError)
-		flagCache   = flags.String(flagNameCache, "", "")
-		flagForce   = flags.Bool(flagNameForce, false, "")
-		flagNoCache = flags.Bool(flagNameNoCache, false, "")
-		flagNoEvict = flags.Bool(flagNameNoEvict, false, "")
+		flags        = flag.NewFlagSet(cmdNameUpdate, flag.ContinueOnError)
+		flagAlgo     = flags.String(flagNameAlgo, checksum.BestAlgo.String(), "")
+		flagCache    = flags.String(flagNameCache, "", "")
+		flagCheck    = flags.Bool(flagNameCheck, false, "")
+		flagDryRun   = flags.Bool(flagNameDryRun, false, "")
+		flagForce    = flags.Bool(flagNameForce, false, "")
+		flagJobs     = flags.Int(flagNameJobs, runtime.NumCPU(), "")
+		flagLimits   = addLimitFlags(flags)
+		flagManifest = flags.Bool(flagNameManifest, false, "")
+		flagNoCache  = flags.Bool(flagNameNoCache, false, "")
+		flagNoEvict  = flags.Bool(flagNameNoEvict, false, "")
+		flagRemote   = flags.String(flagNameRemote, "", "")
+		flagSumfile  = flags.String(flagNameSumfile, "", "")
 	)
 
 	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
@@ -52,31 +63,130 @@ func cmdUpdate(argv []string) error {
 		return errors.Join(errUnexpected, err)
 	}
 
-	c, err := cache.New(*flagCache, *flagNoCache)
+	algo, err := checksum.ParseName(*flagAlgo)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	limits, err := flagLimits.limits()
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	jobs, err := getJobs(*flagJobs)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	c, err := getCache(*flagCache, *flagNoCache, *flagRemote, false, limits)
 	if err != nil {
 		return errors.Join(errCache, err)
 	}
 
-	if !*flagNoEvict {
-		if err := c.Evict(); err != nil {
+	preview := *flagDryRun || *flagCheck
+	if preview {
+		c = cache.NewReadOnly(c)
+	} else if !*flagNoEvict {
+		if err := evict(c); err != nil {
 			return errors.Join(errUnexpected, err)
 		}
 	}
 
 	cfg := ghasum.Config{
-		Repo:  os.DirFS(target),
-		Path:  target,
-		Cache: c,
+		Repo:      os.DirFS(target),
+		Path:      target,
+		Sumfile:   *flagSumfile,
+		Cache:     c,
+		Manifest:  *flagManifest,
+		Algo:      algo,
+		Limits:    limits,
+		Jobs:      jobs,
+		Generator: generator,
 	}
 
-	if err := ghasum.Update(&cfg, *flagForce); err != nil {
+	if preview {
+		return previewUpdate(&cfg, *flagForce, *flagDryRun, *flagCheck)
+	}
+
+	problems, err := ghasum.Update(&cfg, *flagForce)
+	if err != nil {
 		return errors.Join(errUnexpected, err)
 	}
 
+	if len(problems) > 0 {
+		return errors.Join(errFailure, updateProblems(problems))
+	}
+
 	fmt.Println("Ok")
 	return nil
 }
 
+// previewUpdate computes the changes an update would make without making them.
+// The changes are printed if show is set, and any change is a failure if check
+// is set.
+func previewUpdate(cfg *ghasum.Config, force, show, check bool) error {
+	changes, problems, err := ghasum.Diff(cfg, force)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	if len(problems) > 0 {
+		return errors.Join(errFailure, updateProblems(problems))
+	}
+
+	var added, removed, changed int
+	for _, change := range changes {
+		switch {
+		case change.Old == "":
+			added++
+		case change.New == "":
+			removed++
+		default:
+			changed++
+		}
+	}
+
+	summary := fmt.Sprintf("%d added, %d removed, %d changed", added, removed, changed)
+	if show {
+		for i, change := range changes {
+			if i == 0 || changes[i-1].File != change.File {
+				fmt.Printf("--- %s\n+++ %s\n", change.File, change.File)
+			}
+
+			if change.Old != "" {
+				fmt.Printf("-%s %s\n", change.ID, change.Old)
+			}
+
+			if change.New != "" {
+				fmt.Printf("+%s %s\n", change.ID, change.New)
+			}
+		}
+
+		fmt.Println(summary)
+	}
+
+	if check && len(changes) > 0 {
+		return errors.Join(errFailure, fmt.Errorf("checksums are out of date: %s", summary))
+	}
+
+	if !show {
+		fmt.Println("Ok")
+	}
+
+	return nil
+}
+
+// updateProblems returns an error listing the given problems of an update.
+func updateProblems(problems []ghasum.Problem) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d problems(s) occurred during update:\n", len(problems)))
+	for _, problem := range problems {
+		sb.WriteString(fmt.Sprintf("  %s\n", problem))
+	}
+
+	return errors.New(sb.String())
+}
+
 func helpUpdate() string {
 	return `usage: ghasum update [flags] [target]
 
@@ -84,18 +194,58 @@ Update the checksums in the gha.sum file for the target's current Actions. If no
 target is provided it will default to the current working directory.
 
 If ghasum is not yet initialized this command errors (see "ghasum help init").
+If an Action contains a symbolic link that points outside of its repository
+this command reports it as a problem and the gha.sum file is not changed.
+
+If the gha.sum file uses the split layout (see "ghasum help init") the checksum
+file of every workflow is updated. Checksum files are created for new workflows
+and removed for workflows that no longer exist.
+
+With -dry-run the gha.sum file is not changed, instead the checksums that would
+be added, removed, or (with -force) changed are printed as a diff followed by a
+summary. With -check the gha.sum file is not changed either, instead this
+command errors with a non-zero exit code if it is out of date. Neither locks the
+gha.sum file, so they can be used while another command is running, nor changes
+the cache.
 
 The available flags are:
 
+    -algo name
+        The hashing algorithm to compute new checksums with, one of sha256,
+        sha512, blake3, or sha256-modes. Only sha256-modes covers the modes of
+        files and the targets of symbolic links.
+        Defaults to sha256.
     -cache dir
         The location of the cache directory. This is where ghasum stores and
         looks up repositories it needs.
-        Defaults to a directory named .ghasum in the user's home directory.
+        Defaults to a directory named ghasum in $XDG_CACHE_HOME if it is set,
+        or a directory named .ghasum in the user's home directory otherwise.
+    -check
+        Only check whether the gha.sum file is up-to-date, exiting with a
+        non-zero exit code if it is not.
+    -dry-run
+        Print the changes to the gha.sum file instead of making them.
     -force
         Force updating the gha.sum file, ignoring syntax errors and fixing them
         in the process. This also fixes any existing checksums that are wrong.
+    -jobs count
+        The maximum number of Actions to fetch and hash at the same time.
+        Defaults to the number of CPUs.
+    -manifest
+        Store the hashes of the files covered by every checksum in the file
+        gha.sum.manifest next to the gha.sum file, which is used to explain
+        checksum mismatches. An existing manifest is always kept up-to-date.
+` + helpLimitFlags + `
     -no-cache
         Disable the use of the cache. Makes the -cache flag ineffective.
     -no-evict
-        Disable cache eviction.`
+        Disable cache eviction.
+    -remote-cache url
+        The url of a remote cache (see "ghasum help cache"). Repositories that
+        are fetched are uploaded to it. Repositories are never downloaded from
+        it, so the checksums are always computed from repositories on GitHub.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
 }
@@ -24,7 +24,11 @@ func TestUpdate(t *testing.T) {
 	t.Parallel()
 
 	params := testscript.Params{
-		Dir: "../../testdata/update",
+		Dir:   "../../testdata/update",
+		Setup: setup,
+		Cmds: map[string]func(ts *testscript.TestScript, neg bool, args []string){
+			"age": age,
+		},
 	}
 
 	testscript.Run(t, params)
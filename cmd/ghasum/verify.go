@@ -21,19 +21,27 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 
-	"github.com/ericcornelissen/ghasum/internal/cache"
 	"github.com/ericcornelissen/ghasum/internal/ghasum"
+	"github.com/ericcornelissen/ghasum/internal/sshsig"
 )
 
 func cmdVerify(argv []string) error {
 	var (
-		flags       = flag.NewFlagSet(cmdNameVerify, flag.ContinueOnError)
-		flagCache   = flags.String(flagNameCache, "", "")
-		flagNoCache = flags.Bool(flagNameNoCache, false, "")
-		flagNoEvict = flags.Bool(flagNameNoEvict, false, "")
-		flagOffline = flags.Bool(flagNameOffline, false, "")
+		flags              = flag.NewFlagSet(cmdNameVerify, flag.ContinueOnError)
+		flagAllowedSigners = flags.String(flagNameAllowedSigners, "", "")
+		flagCache          = flags.String(flagNameCache, "", "")
+		flagJobs           = flags.Int(flagNameJobs, runtime.NumCPU(), "")
+		flagLimits         = addLimitFlags(flags)
+		flagNoCache        = flags.Bool(flagNameNoCache, false, "")
+		flagNoEvict        = flags.Bool(flagNameNoEvict, false, "")
+		flagOffline        = flags.Bool(flagNameOffline, false, "")
+		flagRemote         = flags.String(flagNameRemote, "", "")
+		flagRequireSig     = flags.Bool(flagNameRequireSig, false, "")
+		flagStrict         = flags.Bool(flagNameStrict, false, "")
+		flagSumfile        = flags.String(flagNameSumfile, "", "")
 	)
 
 	flags.Usage = func() { fmt.Fprintln(os.Stderr) }
@@ -42,7 +50,7 @@ func cmdVerify(argv []string) error {
 	}
 
 	args := flags.Args()
-	if len(args) > 1 {
+	if len(args) > 1 || (*flagRequireSig && *flagAllowedSigners == "") {
 		return errUsage
 	}
 
@@ -69,13 +77,30 @@ func cmdVerify(argv []string) error {
 		target = repo
 	}
 
-	c, err := cache.New(*flagCache, *flagNoCache)
+	limits, err := flagLimits.limits()
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	jobs, err := getJobs(*flagJobs)
+	if err != nil {
+		return errors.Join(errUnexpected, err)
+	}
+
+	var signers []sshsig.AllowedSigner
+	if *flagRequireSig {
+		if signers, err = getSigners(*flagAllowedSigners); err != nil {
+			return errors.Join(errUnexpected, err)
+		}
+	}
+
+	c, err := getCache(*flagCache, *flagNoCache, *flagRemote, true, limits)
 	if err != nil {
 		return errors.Join(errCache, err)
 	}
 
 	if !*flagNoEvict {
-		if evictErr := c.Evict(); evictErr != nil {
+		if evictErr := evict(c); evictErr != nil {
 			return errors.Join(errUnexpected, evictErr)
 		}
 	}
@@ -83,10 +108,15 @@ func cmdVerify(argv []string) error {
 	cfg := ghasum.Config{
 		Repo:     os.DirFS(target),
 		Path:     target,
+		Sumfile:  *flagSumfile,
 		Workflow: workflow,
 		Job:      job,
 		Cache:    c,
 		Offline:  *flagOffline,
+		Limits:   limits,
+		Jobs:     jobs,
+		Signers:  signers,
+		Strict:   *flagStrict,
 	}
 
 	problems, err := ghasum.Verify(&cfg)
@@ -113,7 +143,8 @@ func helpVerify() string {
 
 Verify the Actions in the target against the stored checksums. If no target is
 provided it will default to the current working directory. If the checksums do
-not match this command will error with a non-zero exit code. If ghasum is not
+not match, or if an Action contains a symbolic link that points outside of its
+repository, this command will error with a non-zero exit code. If ghasum is not
 yet initialized this command errors (see "ghasum help init").
 
 The target can be either a directory or a file. If it is a directory it must be
@@ -134,17 +165,54 @@ it is a file it may specify a job by using a ":job" suffix. For example:
 
 In this case checksums will be verified only for the given job in the workflow.
 
+If the gha.sum file uses the split layout (see "ghasum help init") the Actions
+of every workflow are verified against the checksum file of that workflow only.
+A checksum file that is missing is treated as one without checksums.
+
+With -require-signature the gha.sum file must also be signed by a trusted signer
+(see "ghasum help sign"), otherwise this command errors with a non-zero exit
+code. The trusted signers are listed in an allowed signers file in the format
+used by ssh-keygen, where the namespace "ghasum" must be allowed for a signer if
+it is restricted. If the gha.sum file uses the split layout this applies to the
+checksum file of every workflow that is verified as well.
+
+With -strict this command also errors if the gha.sum file contains checksums for
+Actions that are not used, is not formatted (see "ghasum help fmt"), or does not
+use the latest sumfile version (see "ghasum help migrate"). This can be used to
+check that the gha.sum file is exactly in sync with the workflows.
+
 The available flags are:
 
+    -allowed-signers file
+        The allowed signers file listing the trusted signers. Required with
+        -require-signature.
     -cache dir
         The location of the cache directory. This is where ghasum stores and
         looks up repositories it needs.
-        Defaults to a directory named .ghasum in the user's home directory.
+        Defaults to a directory named ghasum in $XDG_CACHE_HOME if it is set,
+        or a directory named .ghasum in the user's home directory otherwise.
+    -jobs count
+        The maximum number of Actions to fetch and hash at the same time.
+        Defaults to the number of CPUs.
+` + helpLimitFlags + `
     -no-cache
         Disable the use of the cache. Makes the -cache flag ineffective.
     -no-evict
         Disable cache eviction.
     -offline
         Run without fetching repositories from the internet, verify exclusively
-        against the cache. If the cache is missing an entry it causes an error.`
+        against the cache. If the cache is missing an entry it causes an error.
+    -remote-cache url
+        The url of a remote cache (see "ghasum help cache"). Repositories that
+        are missing from the cache are downloaded from it before they're fetched
+        and repositories that are fetched are uploaded to it. With -offline
+        repositories are still downloaded from the remote cache.
+    -require-signature
+        Require the checksums to be signed by a trusted signer.
+    -strict
+        Also error on redundant checksums, formatting, and outdated versions.
+    -sumfile path
+        The path of the gha.sum file relative to the target.
+        Defaults to .github/workflows/gha.sum, or the path in its sumfile
+        header if it has one.`
 }
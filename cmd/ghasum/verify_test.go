@@ -25,6 +25,9 @@ func TestVerify(t *testing.T) {
 
 	params := testscript.Params{
 		Dir: "../../testdata/verify",
+		Cmds: map[string]func(ts *testscript.TestScript, neg bool, args []string){
+			"age": age,
+		},
 	}
 
 	testscript.Run(t, params)
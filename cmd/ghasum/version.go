@@ -22,6 +22,9 @@ import (
 
 const version = "0.2.0"
 
+// generator identifies this program in the sumfiles it writes.
+const generator = "ghasum v" + version
+
 func cmdVersion(argv []string) error {
 	var (
 		flags = flag.NewFlagSet(cmdNameVersion, flag.ContinueOnError)
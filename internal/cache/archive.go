@@ -0,0 +1,352 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ericcornelissen/ghasum/internal/github"
+	"github.com/ericcornelissen/ghasum/internal/vfs"
+)
+
+// archiveVersion is the version of the archive format produced by Export.
+const archiveVersion = 1
+
+// archiveIndex is the name of the file in an archive that describes the archive.
+const archiveIndex = "ghasum-archive"
+
+// archiveIndexSize is the maximum size, in bytes, of the archive index.
+const archiveIndexSize = 1 << 20
+
+// archiveWindowSize is the maximum size, in bytes, of the window used to
+// compress and decompress archives.
+const archiveWindowSize = 8 << 20
+
+// Export writes an archive containing the given entries of the cache to w. An
+// entry is identified by its key, for example "actions/checkout/v4".
+func Export(c Cache, w io.Writer, entries []string) error {
+	files := make([]fs.FS, len(entries))
+	for i, entry := range entries {
+		if err := validEntry(entry); err != nil {
+			return err
+		}
+
+		entryFiles, err := c.Lookup(entry)
+		if err != nil {
+			return fmt.Errorf("missing %q from cache", entry)
+		}
+
+		files[i] = entryFiles
+	}
+
+	zw, err := zstd.NewWriter(w, zstd.WithWindowSize(archiveWindowSize))
+	if err != nil {
+		return fmt.Errorf("could not create archive: %v", err)
+	}
+
+	tw := tar.NewWriter(zw)
+	if err := writeIndex(tw, entries); err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if err := writeEntry(tw, entry, files[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("could not write archive: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("could not write archive: %v", err)
+	}
+
+	return nil
+}
+
+// Import reads an archive created by Export from r and adds its entries to the
+// cache. Every entry is unpacked and given to verify before anything is added
+// to the cache. If verify errors for any entry nothing is added to the cache.
+//
+// The archive is unpacked in memory, so the number of files and total size of
+// the files in the archive are limited by the given limits. A limit of 0 is not
+// enforced.
+func Import(c Cache, r io.Reader, limits github.Limits, verify func(entry string, files fs.FS) error) error {
+	entries, files, err := unpack(r, limits)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if err := verify(entry, files[i]); err != nil {
+			return fmt.Errorf("could not verify %q: %v", entry, err)
+		}
+	}
+
+	for i, entry := range entries {
+		if err := c.Store(entry, files[i]); err != nil {
+			return fmt.Errorf("could not import %q: %v", entry, err)
+		}
+	}
+
+	return nil
+}
+
+func writeIndex(tw *tar.Writer, entries []string) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("version %d\n\n", archiveVersion))
+	for _, entry := range entries {
+		sb.WriteString(entry)
+		sb.WriteRune('\n')
+	}
+
+	index := sb.String()
+	header := tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     archiveIndex,
+		Mode:     0o600,
+		Size:     int64(len(index)),
+	}
+
+	if err := tw.WriteHeader(&header); err != nil {
+		return fmt.Errorf("could not write archive index: %v", err)
+	}
+
+	if _, err := io.WriteString(tw, index); err != nil {
+		return fmt.Errorf("could not write archive index: %v", err)
+	}
+
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, entry string, files fs.FS) error {
+	walk := func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if files, ok := files.(readLinkFS); ok {
+				link, err = files.ReadLink(name)
+			} else {
+				info, err = fs.Stat(files, name)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		header.Name = path.Join(entry, name)
+		header.ModTime = header.ModTime.UTC()
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := files.Open(name)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	}
+
+	if err := fs.WalkDir(files, ".", walk); err != nil {
+		return fmt.Errorf("could not archive %q: %v", entry, err)
+	}
+
+	return nil
+}
+
+func unpack(r io.Reader, limits github.Limits) ([]string, []fs.FS, error) {
+	zr, err := zstd.NewReader(
+		r,
+		zstd.WithDecoderMaxMemory(archiveWindowSize),
+		zstd.WithDecoderMaxWindow(archiveWindowSize),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read archive: %v", err)
+	}
+
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	entries, err := readIndex(tr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var count int
+	var size int64
+
+	unpacked := make(map[string]*vfs.FS, len(entries))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("could not read archive: %v", err)
+		}
+
+		count, size = count+1, size+header.Size
+		if limits.Files > 0 && count > limits.Files {
+			return nil, nil, fmt.Errorf("archive has more than %d files", limits.Files)
+		} else if limits.Size > 0 && size > limits.Size {
+			return nil, nil, fmt.Errorf("archive is larger than %d bytes", limits.Size)
+		}
+
+		entry, name, err := splitMember(header.Name, entries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files, ok := unpacked[entry]
+		if !ok {
+			files = vfs.New()
+			unpacked[entry] = files
+		}
+
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = files.MkdirAll(name, mode)
+		case tar.TypeReg:
+			var data []byte
+			if data, err = io.ReadAll(io.LimitReader(tr, header.Size)); err == nil {
+				err = files.WriteFile(name, data, mode)
+			}
+		case tar.TypeSymlink:
+			err = files.Symlink(header.Linkname, name)
+		default:
+			err = fmt.Errorf("unsupported file type %q", header.Typeflag)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unpack %q: %v", header.Name, err)
+		}
+	}
+
+	files := make([]fs.FS, len(entries))
+	for i, entry := range entries {
+		entryFiles, ok := unpacked[entry]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %q", entry)
+		}
+
+		files[i] = entryFiles
+	}
+
+	return entries, files, nil
+}
+
+func readIndex(tr *tar.Reader) ([]string, error) {
+	header, err := tr.Next()
+	if err != nil || header.Name != archiveIndex {
+		return nil, errors.New("archive index not found")
+	}
+
+	if header.Size > archiveIndexSize {
+		return nil, errors.New("archive index is too large")
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(tr, archiveIndexSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not read archive index: %v", err)
+	}
+
+	lines := strings.Split(string(raw), "\n")
+	if len(lines) < 3 || lines[1] != "" || lines[len(lines)-1] != "" {
+		return nil, errors.New("archive index is corrupted")
+	}
+
+	version, ok := strings.CutPrefix(lines[0], "version ")
+	if !ok {
+		return nil, errors.New("archive version not found")
+	}
+
+	if v, err := strconv.Atoi(version); err != nil || v != archiveVersion {
+		return nil, fmt.Errorf("unknown archive version %s", version)
+	}
+
+	entries := lines[2 : len(lines)-1]
+	for _, entry := range entries {
+		if err := validEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func splitMember(member string, entries []string) (string, string, error) {
+	if !fs.ValidPath(member) {
+		return "", "", fmt.Errorf("invalid path %q in archive", member)
+	}
+
+	parts := strings.SplitN(member, "/", 4)
+	if len(parts) < 3 || !slices.Contains(entries, path.Join(parts[:3]...)) {
+		return "", "", fmt.Errorf("unexpected path %q in archive", member)
+	}
+
+	name := "."
+	if len(parts) == 4 {
+		name = parts[3]
+	}
+
+	return path.Join(parts[:3]...), name, nil
+}
+
+// validEntry checks that the given entry is a valid key of the form
+// "owner/project/ref". The ref may not start with a dot, so that an entry can
+// never refer to the git object store (".git") or a temporary directory of the
+// cache.
+func validEntry(entry string) error {
+	if !fs.ValidPath(entry) || strings.Count(entry, "/") != 2 || strings.HasPrefix(path.Base(entry), ".") {
+		return fmt.Errorf("invalid cache entry %q", entry)
+	}
+
+	return nil
+}
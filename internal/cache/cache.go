@@ -15,117 +15,57 @@
 package cache
 
 import (
-	"fmt"
+	"errors"
 	"io/fs"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
-)
-
-// Cache represents a cache located on the file system.
-type Cache struct {
-	// Path is the path of the cache on the file system.
-	path string
-
-	// Ephemeral marks the cache as such, locating it in the system's temporary
-	// directory and
-	ephemeral bool
-}
 
-// Cleanup removes the cache if it is ephemeral, ignoring errors.
-func (c *Cache) Cleanup() {
-	if c.ephemeral {
-		_ = c.Clear()
-	}
-}
-
-// Clear removes the contents of the cache.
-func (c *Cache) Clear() error {
-	if err := os.RemoveAll(c.path); err != nil {
-		return fmt.Errorf("could not clear %q: %v", c.path, err)
-	}
-
-	return nil
-}
+	"github.com/go-git/go-git/v5/storage"
+)
 
-// Evict removes old entries from the cache.
-func (c *Cache) Evict() error {
-	deadline := time.Now().AddDate(0, 0, -5)
-	walk := func(path string, entry fs.DirEntry, _ error) error {
-		depth := strings.Count(path, string(os.PathSeparator))
-		if depth < 2 {
-			return nil
-		}
+// Cache represents a cache of repositories. An entry in the cache holds the
+// files of a repository at a specific ref and is identified by a key of the
+// form "owner/project/ref".
+type Cache interface {
+	// Cleanup releases temporary resources held by the cache, ignoring errors.
+	Cleanup()
 
-		info, err := entry.Info()
-		if err != nil {
-			return fmt.Errorf("could not get file info for %q", path)
-		}
+	// Evict removes old entries from the cache.
+	Evict() error
 
-		if info.ModTime().Before(deadline) {
-			_ = os.RemoveAll(filepath.Join(c.path, path))
-			return fs.SkipDir
-		}
+	// Init sets up the cache (if necessary).
+	Init() error
 
-		return fs.SkipDir
-	}
+	// Lookup returns the files of the entry with the given key. If there is no
+	// such entry in the cache ErrMissing is returned.
+	Lookup(key string) (fs.FS, error)
 
-	fsys := os.DirFS(c.path)
-	if err := fs.WalkDir(fsys, ".", walk); err != nil {
-		return fmt.Errorf("cache eviction failed: %v", err)
-	}
+	// Objects returns the git object store of the repository identified by the
+	// given key of the form "owner/project". The object store is shared by all
+	// entries for the repository.
+	Objects(key string) (storage.Storer, error)
 
-	return nil
+	// Store adds an entry with the given files to the cache, replacing any
+	// existing entry with the same key.
+	Store(key string, files fs.FS) error
 }
 
-// Init sets up the cache (if necessary).
-func (c *Cache) Init() error {
-	if c.ephemeral {
-		location, err := os.MkdirTemp(os.TempDir(), "ghasum-clone-*")
-		if err != nil {
-			return fmt.Errorf("could not create temporary cache: %v", err)
-		}
-
-		c.path = location
-	} else {
-		if err := os.MkdirAll(c.path, 0o700); err != nil {
-			return fmt.Errorf("could not create cache at %q: %v", c.path, err)
-		}
-	}
-
-	return nil
-}
+// Quarantine represents a cache that keeps entries it does not trust apart from
+// its other entries, until they are promoted.
+type Quarantine interface {
+	Cache
 
-// Path returns the path to the cache on the file system.
-func (c *Cache) Path() string {
-	return c.path
+	// Promote marks the entry with the given key as trusted. It should only be
+	// called after the files of the entry are found to match a known checksum.
+	// If the entry is not in quarantine nothing happens.
+	Promote(key string) error
 }
 
-// New creates an uninitialized cache.
-//
-// If location is an empty string the location will default to the user's home
-// directory.
-//
-// If ephemeral is set the cache will be located in a unique directory in the
-// system's temporary directory (and the given location is ignored).
-func New(location string, ephemeral bool) (Cache, error) {
-	var c Cache
-
-	if ephemeral {
-		c.ephemeral = true
-	} else {
-		if location == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return c, fmt.Errorf("could not get user home directory: %v", err)
-			}
+// ErrMissing is the error when an entry is not in the cache.
+var ErrMissing = errors.New("missing from cache")
 
-			c.path = filepath.Join(home, ".ghasum")
-		} else {
-			c.path = location
-		}
-	}
+// readLinkFS is a file system that supports reading symbolic links.
+type readLinkFS interface {
+	fs.FS
 
-	return c, nil
+	// ReadLink returns the destination of the named symbolic link.
+	ReadLink(name string) (string, error)
 }
@@ -0,0 +1,320 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gitcache "github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Dir represents a cache located on the file system.
+type Dir struct {
+	// Path is the path of the cache on the file system.
+	path string
+}
+
+// dirFS is the file system hierarchy of an entry in a Dir cache.
+type dirFS struct {
+	fs.FS
+
+	root string
+}
+
+// Cleanup does nothing, the cache is persistent.
+func (c *Dir) Cleanup() {}
+
+// Clear removes the contents of the cache.
+func (c *Dir) Clear() error {
+	if err := os.RemoveAll(c.path); err != nil {
+		return fmt.Errorf("could not clear %q: %v", c.path, err)
+	}
+
+	return nil
+}
+
+// Evict removes old entries from the cache.
+func (c *Dir) Evict() error {
+	deadline := time.Now().AddDate(0, 0, -5)
+	walk := func(path string, entry fs.DirEntry, _ error) error {
+		depth := strings.Count(path, string(os.PathSeparator))
+		if depth < 2 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("could not get file info for %q", path)
+		}
+
+		if info.ModTime().Before(deadline) {
+			_ = os.RemoveAll(filepath.Join(c.path, path))
+			return fs.SkipDir
+		}
+
+		return fs.SkipDir
+	}
+
+	fsys := os.DirFS(c.path)
+	if err := fs.WalkDir(fsys, ".", walk); err != nil {
+		return fmt.Errorf("cache eviction failed: %v", err)
+	}
+
+	return nil
+}
+
+// Init sets up the cache (if necessary).
+func (c *Dir) Init() error {
+	if err := os.MkdirAll(c.path, 0o700); err != nil {
+		return fmt.Errorf("could not create cache at %q: %v", c.path, err)
+	}
+
+	if err := c.secure(); err != nil {
+		return fmt.Errorf("unsafe cache at %q: %v", c.path, err)
+	}
+
+	return nil
+}
+
+// Lookup returns the files of the entry with the given key.
+func (c *Dir) Lookup(key string) (fs.FS, error) {
+	if err := validEntry(key); err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(c.path, filepath.FromSlash(key))
+	if _, err := os.Stat(root); err != nil {
+		return nil, ErrMissing
+	}
+
+	files := dirFS{
+		FS:   os.DirFS(root),
+		root: root,
+	}
+
+	return files, nil
+}
+
+// Objects returns the git object store of the repository identified by the
+// given key. The object store is located in the ".git" directory next to the
+// entries for the repository.
+func (c *Dir) Objects(key string) (storage.Storer, error) {
+	if !fs.ValidPath(key) || strings.Count(key, "/") != 1 {
+		return nil, fmt.Errorf("invalid repository %q", key)
+	}
+
+	dir := filepath.Join(c.path, filepath.FromSlash(key), ".git")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create object store at %q: %v", dir, err)
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+
+	objects := filesystem.NewStorage(osfs.New(dir), gitcache.NewObjectLRUDefault())
+	return objects, nil
+}
+
+// Path returns the path to the cache on the file system.
+func (c *Dir) Path() string {
+	return c.path
+}
+
+// Store writes the given files to the cache as the entry with the given key.
+func (c *Dir) Store(key string, files fs.FS) error {
+	if err := validEntry(key); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(c.path, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return fmt.Errorf("could not create %q: %v", filepath.Dir(dst), err)
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not store %q: %v", key, err)
+	}
+
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	if err := write(tmp, files); err != nil {
+		return fmt.Errorf("could not store %q: %v", key, err)
+	}
+
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("could not replace %q: %v", key, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("could not store %q: %v", key, err)
+	}
+
+	return nil
+}
+
+// secure verifies that the cache and its entries can be trusted, down to the
+// root directory of every entry.
+func (c *Dir) secure() error {
+	walk := func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("could not get file info for %q", path)
+		}
+
+		if err := secureDir(filepath.Join(c.path, path), info); err != nil {
+			return err
+		}
+
+		if path != "." && strings.Count(path, "/") >= 2 {
+			return fs.SkipDir
+		}
+
+		return nil
+	}
+
+	return fs.WalkDir(os.DirFS(c.path), ".", walk)
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (f dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return os.Readlink(filepath.Join(f.root, filepath.FromSlash(name)))
+}
+
+// New creates an uninitialized cache on the file system.
+//
+// If location is an empty string the location will default to a directory
+// named ghasum in $XDG_CACHE_HOME, if set, or .ghasum in the user's home
+// directory otherwise.
+func New(location string) (*Dir, error) {
+	if location == "" {
+		var err error
+		if location, err = defaultLocation(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Dir{path: location}, nil
+}
+
+// EvictTemporary removes leftover temporary caches, located in the system's
+// temporary directory, that were created by earlier versions of ghasum when
+// the cache was disabled. Temporary caches that may still be in use are not
+// removed. Errors are ignored.
+func EvictTemporary() {
+	deadline := time.Now().Add(-1 * time.Hour)
+
+	leftovers, _ := filepath.Glob(filepath.Join(os.TempDir(), "ghasum-clone-*"))
+	for _, leftover := range leftovers {
+		info, err := os.Lstat(leftover)
+		if err != nil || !info.IsDir() || info.ModTime().After(deadline) {
+			continue
+		}
+
+		_ = os.RemoveAll(leftover)
+	}
+}
+
+func defaultLocation() (string, error) {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "ghasum"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %v", err)
+	}
+
+	return filepath.Join(home, ".ghasum"), nil
+}
+
+func write(dir string, files fs.FS) error {
+	walk := func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if files, ok := files.(readLinkFS); ok {
+				target, err := files.ReadLink(name)
+				if err != nil {
+					return err
+				}
+
+				return os.Symlink(target, dst)
+			}
+		}
+
+		info, err := fs.Stat(files, name)
+		if err != nil {
+			return err
+		}
+
+		src, err := files.Open(name)
+		if err != nil {
+			return err
+		}
+
+		defer src.Close()
+
+		return writeFile(dst, src, info.Mode())
+	}
+
+	if err := fs.WalkDir(files, ".", walk); err != nil {
+		return fmt.Errorf("could not write files: %v", err)
+	}
+
+	return nil
+}
+
+func writeFile(dst string, r io.Reader, mode fs.FileMode) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// secureDir verifies that the directory at path, with the given info, can be
+// trusted. It is repaired if it is writable by other users and rejected if it
+// is owned by another user.
+func secureDir(path string, info fs.FileInfo) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%q is owned by another user", path)
+	}
+
+	if perm := info.Mode().Perm(); perm&0o022 != 0 {
+		if err := os.Chmod(path, perm&^0o022); err != nil {
+			return fmt.Errorf("%q is writable by other users: %v", path, err)
+		}
+	}
+
+	return nil
+}
@@ -12,6 +12,6 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package cache provides functionality for managing a cache located on the
-// file system.
+// Package cache provides functionality for managing a cache of repositories,
+// located either on the file system or in memory.
 package cache
@@ -0,0 +1,100 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"io/fs"
+	"sync"
+
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Memory represents a cache located in memory. Its entries are lost when the
+// cache is no longer used.
+type Memory struct {
+	entries map[string]fs.FS
+	objects map[string]storage.Storer
+	mu      sync.Mutex
+}
+
+// Cleanup does nothing, the memory used by the cache is released when the cache
+// is no longer used.
+func (c *Memory) Cleanup() {}
+
+// Evict removes all entries from the cache, as well as the git object stores.
+func (c *Memory) Evict() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]fs.FS, 0)
+	c.objects = make(map[string]storage.Storer, 0)
+	return nil
+}
+
+// Init does nothing, the cache is set up when it is created.
+func (c *Memory) Init() error {
+	return nil
+}
+
+// Lookup returns the files of the entry with the given key.
+func (c *Memory) Lookup(key string) (fs.FS, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, ok := c.entries[key]
+	if !ok {
+		return nil, ErrMissing
+	}
+
+	return files, nil
+}
+
+// Objects returns the in-memory git object store of the repository identified
+// by the given key.
+func (c *Memory) Objects(key string) (storage.Storer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, ok := c.objects[key]
+	if !ok {
+		objects = memory.NewStorage()
+		c.objects[key] = objects
+	}
+
+	return objects, nil
+}
+
+// Store adds the given files to the cache as the entry with the given key. The
+// files are not copied and so must not change after they are stored.
+func (c *Memory) Store(key string, files fs.FS) error {
+	if err := validEntry(key); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = files
+	return nil
+}
+
+// NewMemory creates an empty cache in memory.
+func NewMemory() *Memory {
+	return &Memory{
+		entries: make(map[string]fs.FS, 0),
+		objects: make(map[string]storage.Storer, 0),
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/go-git/go-git/v5/storage"
+)
+
+// ReadOnly represents a cache that looks up entries in another cache without
+// ever changing it. Entries stored in the cache, as well as the git object
+// stores, are kept in memory instead.
+type ReadOnly struct {
+	base   Cache
+	memory *Memory
+}
+
+// Cleanup releases temporary resources held by the underlying cache.
+func (c *ReadOnly) Cleanup() {
+	c.base.Cleanup()
+}
+
+// Evict does nothing, the underlying cache is never changed.
+func (c *ReadOnly) Evict() error {
+	return nil
+}
+
+// Init sets up the underlying cache (if necessary).
+func (c *ReadOnly) Init() error {
+	return c.base.Init()
+}
+
+// Lookup returns the files of the entry with the given key, from memory if it
+// was stored in the cache or from the underlying cache otherwise.
+func (c *ReadOnly) Lookup(key string) (fs.FS, error) {
+	files, err := c.memory.Lookup(key)
+	if !errors.Is(err, ErrMissing) {
+		return files, err
+	}
+
+	return c.base.Lookup(key)
+}
+
+// Objects returns an in-memory git object store of the repository identified
+// by the given key.
+func (c *ReadOnly) Objects(key string) (storage.Storer, error) {
+	return c.memory.Objects(key)
+}
+
+// Store adds the given files to the cache in memory as the entry with the given
+// key.
+func (c *ReadOnly) Store(key string, files fs.FS) error {
+	return c.memory.Store(key, files)
+}
+
+// NewReadOnly creates a cache in front of the given cache that never changes
+// it.
+func NewReadOnly(base Cache) *ReadOnly {
+	return &ReadOnly{
+		base:   base,
+		memory: NewMemory(),
+	}
+}
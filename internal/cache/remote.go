@@ -0,0 +1,286 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/storage"
+
+	"github.com/ericcornelissen/ghasum/internal/github"
+)
+
+// remoteHost is the host of the repositories in the cache, used to namespace
+// entries in a remote cache.
+const remoteHost = "github.com"
+
+// Remote represents a cache that is shared over HTTP, in front of which sits a
+// local cache. Entries missing from the local cache are downloaded from the
+// remote cache and entries stored in the local cache are uploaded to it.
+//
+// Entries are exchanged as archives (see Export) containing only that entry,
+// located at <url>/github.com/<owner>/<project>/<ref>. Files obtained from the
+// remote cache are not trusted. They are kept in quarantine in memory and only
+// added to the local cache when promoted (see Quarantine).
+type Remote struct {
+	local      Cache
+	quarantine *Memory
+	url        string
+	client     *http.Client
+	limits     github.Limits
+	pull       bool
+}
+
+// Cleanup releases temporary resources held by the local cache.
+func (c *Remote) Cleanup() {
+	c.local.Cleanup()
+}
+
+// Evict removes old entries from the local cache.
+func (c *Remote) Evict() error {
+	return c.local.Evict()
+}
+
+// Init sets up the local cache (if necessary).
+func (c *Remote) Init() error {
+	return c.local.Init()
+}
+
+// Lookup returns the files of the entry with the given key. If the entry is not
+// in the local cache it is downloaded from the remote cache into quarantine.
+// ErrMissing is returned only if the entry is in neither cache, any other
+// failure to download the entry is returned as an error.
+func (c *Remote) Lookup(key string) (fs.FS, error) {
+	files, err := c.local.Lookup(key)
+	if !errors.Is(err, ErrMissing) || !c.pull {
+		return files, err
+	}
+
+	if files, err := c.quarantine.Lookup(key); err == nil {
+		return files, nil
+	}
+
+	if err := c.download(key); errors.Is(err, ErrMissing) {
+		return nil, ErrMissing
+	} else if err != nil {
+		return nil, fmt.Errorf("could not download from remote cache: %v", err)
+	}
+
+	return c.quarantine.Lookup(key)
+}
+
+// Objects returns the git object store of the repository identified by the
+// given key from the local cache.
+func (c *Remote) Objects(key string) (storage.Storer, error) {
+	return c.local.Objects(key)
+}
+
+// Promote adds the entry with the given key that was downloaded from the remote
+// cache to the local cache. If the entry was not downloaded nothing happens.
+func (c *Remote) Promote(key string) error {
+	files, err := c.quarantine.Lookup(key)
+	if errors.Is(err, ErrMissing) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return c.local.Store(key, files)
+}
+
+// Store adds an entry with the given files to the local cache and uploads it to
+// the remote cache. Failing to upload the entry is not considered an error.
+func (c *Remote) Store(key string, files fs.FS) error {
+	if err := c.local.Store(key, files); err != nil {
+		return err
+	}
+
+	_ = c.upload(key)
+	return nil
+}
+
+func (c *Remote) download(key string) error {
+	res, err := c.client.Get(c.url + "/" + remotePath(key))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrMissing
+	} else if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %q", res.Status)
+	}
+
+	verify := func(entry string, _ fs.FS) error {
+		if entry != key {
+			return fmt.Errorf("unexpected entry %q", entry)
+		}
+
+		return nil
+	}
+
+	return Import(c.quarantine, res.Body, c.limits, verify)
+}
+
+func (c *Remote) upload(key string) error {
+	var buf bytes.Buffer
+	if err := Export(c.local, &buf, []string{key}); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.url+"/"+remotePath(key), &buf)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %q", res.Status)
+	}
+
+	return nil
+}
+
+// Handler returns an HTTP handler that serves the given cache as a remote cache
+// (see Remote). Uploaded entries are stored in the given cache as is, so the
+// files served by the handler should not be trusted. Uploaded archives are
+// limited by the given limits (see Import) and, if there is a size limit, the
+// size of an upload may not exceed it either. Uploads are unpacked one at the
+// time to bound the memory used by the handler.
+//
+// The handler does not authenticate requests, anyone who can reach it can store
+// entries in the cache. It is meant to be used on trusted networks only.
+func Handler(c Cache, limits github.Limits) http.Handler {
+	var mu sync.Mutex
+
+	key := func(r *http.Request) string {
+		return strings.Join([]string{
+			r.PathValue("owner"),
+			r.PathValue("project"),
+			r.PathValue("ref"),
+		}, "/")
+	}
+
+	get := func(w http.ResponseWriter, r *http.Request) {
+		key := key(r)
+		if err := validEntry(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := Export(c, &buf, []string{key}); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = io.Copy(w, &buf)
+	}
+
+	put := func(w http.ResponseWriter, r *http.Request) {
+		key := key(r)
+		if err := validEntry(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body := r.Body
+		if limits.Size > 0 {
+			if r.ContentLength > limits.Size {
+				http.Error(w, "archive too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			body = http.MaxBytesReader(w, r.Body, limits.Size)
+		}
+
+		verify := func(entry string, _ fs.FS) error {
+			if entry != key {
+				return fmt.Errorf("unexpected entry %q", entry)
+			}
+
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := Import(c, body, limits, verify); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	pattern := "/" + remoteHost + "/{owner}/{project}/{ref}"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+pattern, get)
+	mux.HandleFunc("PUT "+pattern, put)
+	return mux
+}
+
+// NewRemote creates a cache that is shared over HTTP at the given url, in front
+// of which sits the given local cache.
+//
+// If pull is not set entries are only uploaded to the remote cache and never
+// downloaded from it. Downloaded archives are limited by the given limits (see
+// Import).
+func NewRemote(local Cache, location string, pull bool, limits github.Limits) (*Remote, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote cache url %q", location)
+	}
+
+	c := Remote{
+		local:      local,
+		quarantine: NewMemory(),
+		url:        strings.TrimSuffix(u.String(), "/"),
+		client:     &http.Client{Timeout: 30 * time.Second},
+		limits:     limits,
+		pull:       pull,
+	}
+
+	return &c, nil
+}
+
+func remotePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return remoteHost + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ericcornelissen/ghasum/internal/github"
+	"github.com/ericcornelissen/ghasum/internal/vfs"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	files := vfs.New()
+	if err := files.WriteFile("action.yml", []byte("name: example"), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testCases := map[string]struct {
+		method string
+		path   string
+		size   int64
+		want   int
+	}{
+		"Store an entry": {
+			method: http.MethodPut,
+			path:   "/github.com/actions/checkout/v4",
+			want:   http.StatusNoContent,
+		},
+		"Store an entry exceeding the size limit": {
+			method: http.MethodPut,
+			path:   "/github.com/actions/checkout/v4",
+			size:   10,
+			want:   http.StatusRequestEntityTooLarge,
+		},
+		"Store the git object store": {
+			method: http.MethodPut,
+			path:   "/github.com/actions/checkout/.git",
+			want:   http.StatusBadRequest,
+		},
+		"Store a hidden entry": {
+			method: http.MethodPut,
+			path:   "/github.com/actions/checkout/.tmp-123",
+			want:   http.StatusBadRequest,
+		},
+		"Retrieve an entry": {
+			method: http.MethodGet,
+			path:   "/github.com/actions/checkout/v4",
+			want:   http.StatusOK,
+		},
+		"Retrieve a missing entry": {
+			method: http.MethodGet,
+			path:   "/github.com/actions/checkout/v5",
+			want:   http.StatusNotFound,
+		},
+		"Retrieve the git object store": {
+			method: http.MethodGet,
+			path:   "/github.com/actions/checkout/.git",
+			want:   http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewMemory()
+			if tt.method == http.MethodGet {
+				if err := c.Store("actions/checkout/v4", files); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			key := strings.TrimPrefix(tt.path, "/github.com/")
+			body := archive(t, key, files)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(body))
+			res := httptest.NewRecorder()
+			limits := github.DefaultLimits
+			if tt.size > 0 {
+				limits.Size = tt.size
+			}
+
+			Handler(c, limits).ServeHTTP(res, req)
+
+			if got := res.Code; got != tt.want {
+				t.Errorf("Unexpected status (got %d, want %d)", got, tt.want)
+			}
+		})
+	}
+}
+
+// archive creates an archive containing only the given entry, without
+// validating the entry.
+func archive(t *testing.T, entry string, files fs.FS) []byte {
+	var buf bytes.Buffer
+
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tw := tar.NewWriter(zw)
+	if err := writeIndex(tw, []string{entry}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := writeEntry(tw, entry, files); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestRemoteLookup(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		status  int
+		missing bool
+	}{
+		"Not found": {
+			status:  http.StatusNotFound,
+			missing: true,
+		},
+		"Server error": {
+			status:  http.StatusInternalServerError,
+			missing: false,
+		},
+		"Invalid archive": {
+			status:  http.StatusOK,
+			missing: false,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			c, err := NewRemote(NewMemory(), server.URL, true, github.DefaultLimits)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, err = c.Lookup("actions/checkout/v4")
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+
+			if got := errors.Is(err, ErrMissing); got != tt.missing {
+				t.Errorf("Unexpected error (got %v)", err)
+			}
+		})
+	}
+}
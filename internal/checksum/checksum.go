@@ -15,9 +15,20 @@
 package checksum
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 
-	"golang.org/x/mod/sumdb/dirhash"
+	"lukechampine.com/blake3"
 )
 
 // Algo represents a cryptographic hash algorithm.
@@ -27,22 +38,410 @@ const (
 	// Sha256 identifies the SHA256 hashing algorithm.
 	Sha256 Algo = iota
 
+	// Sha512 identifies the SHA512 hashing algorithm.
+	Sha512
+
+	// Blake3 identifies the BLAKE3 hashing algorithm.
+	Blake3
+
+	// Sha256Modes identifies the SHA256 hashing algorithm covering, besides the
+	// names and contents of files, their modes and the targets of symbolic
+	// links.
+	Sha256Modes
+
 	// BestAlgo identifies the best available hashing algorithm.
 	BestAlgo = Sha256
 )
 
-var hashes = map[Algo]dirhash.Hash{
-	Sha256: dirhash.Hash1,
+// Limits bound the file system hierarchies that are hashed. A limit with the
+// zero value is not enforced.
+type Limits struct {
+	// Files is the maximum number of files.
+	Files int
+
+	// Size is the maximum total size, in bytes, of the files.
+	Size int64
+}
+
+// A Manifest maps the name of every file covered by a checksum to the hex
+// encoded hash of its content, prefixed by its mode if the algorithm covers
+// file modes.
+type Manifest map[string]string
+
+var hashes = map[Algo]func() hash.Hash{
+	Sha256: sha256.New,
+	Sha512: sha512.New,
+	Blake3: func() hash.Hash { return blake3.New(32, nil) },
+
+	Sha256Modes: sha256.New,
+}
+
+// withModes are the algorithms that cover file modes and symbolic links.
+var withModes = map[Algo]bool{
+	Sha256Modes: true,
+}
+
+// tags are the prefixes identifying the algorithm that produced a checksum.
+// Every tag is "h" followed by a number that is unique to the algorithm.
+var tags = map[Algo]string{
+	Sha256: "h1",
+	Sha512: "h3",
+	Blake3: "h4",
+
+	Sha256Modes: "h2",
+}
+
+// names are the human readable names of the algorithms.
+var names = map[Algo]string{
+	Sha256: "sha256",
+	Sha512: "sha512",
+	Blake3: "blake3",
+
+	Sha256Modes: "sha256-modes",
+}
+
+// Modes of files as recorded by algorithms that cover file modes. These follow
+// the modes used by Git.
+const (
+	modeFile       = "100644"
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+)
+
+// readLinkFS is a file system that supports reading symbolic links.
+type readLinkFS interface {
+	ReadLink(name string) (string, error)
 }
 
-// Compute the checksum over the directory at the given path using the specified
-// cryptographic hash algorithm.
-func Compute(path string, algo Algo) (string, error) {
-	hash := hashes[algo]
-	checksum, err := dirhash.HashDir(path, "", hash)
+// maxLinks is the maximum number of symbolic links followed to resolve a single
+// symbolic link.
+const maxLinks = 40
+
+var (
+	// ErrUnknownAlgo is the error when the algorithm of a checksum is not known.
+	ErrUnknownAlgo = errors.New("unknown checksum algorithm")
+
+	// ErrUnsafeSymlink is the error when a symbolic link does not resolve to a
+	// path within the file system hierarchy being hashed.
+	ErrUnsafeSymlink = errors.New("symbolic link escapes the file tree")
+)
+
+// Compute the checksum over the files in the given file system hierarchy using
+// the specified cryptographic hash algorithm.
+func Compute(files fs.FS, algo Algo) (string, error) {
+	checksum, _, err := ComputeManifest(files, algo, Limits{})
+	return checksum, err
+}
+
+// ComputeManifest computes the checksum like Compute and additionally returns
+// the manifest of the files covered by the checksum.
+//
+// The computation fails if the file system hierarchy exceeds any of the given
+// limits.
+//
+// Symbolic links are never followed if they do not resolve to a path within the
+// given file system hierarchy, instead an error wrapping ErrUnsafeSymlink is
+// returned.
+func ComputeManifest(files fs.FS, algo Algo, limits Limits) (string, Manifest, error) {
+	var names []string
+	entries := make(map[string]fs.DirEntry)
+	walk := func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			names = append(names, name)
+			entries[name] = entry
+		}
+
+		return nil
+	}
+
+	if err := fs.WalkDir(files, ".", walk); err != nil {
+		return "", nil, fmt.Errorf("could not compute checksum: %v", err)
+	}
+
+	if err := checkLimits(limits, entries); err != nil {
+		return "", nil, fmt.Errorf("could not compute checksum: %v", err)
+	}
+
+	links, err := readLinks(files, entries)
 	if err != nil {
-		return "", fmt.Errorf("could not compute checksum: %v", err)
+		return "", nil, fmt.Errorf("could not compute checksum: %v", err)
+	}
+
+	for _, name := range names {
+		if target, ok := links[name]; ok && !contained(name, links) {
+			return "", nil, errors.Join(ErrUnsafeSymlink, fmt.Errorf("%q points to %q", name, target))
+		}
+	}
+
+	open := func(name string) (io.ReadCloser, error) {
+		return files.Open(name)
+	}
+
+	describe := hashContent(hashes[algo], open)
+	if withModes[algo] {
+		describe = hashEntry(hashes[algo], files, entries, links)
+	}
+
+	checksum, manifest, err := summarize(tags[algo], hashes[algo], names, describe)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not compute checksum: %v", err)
+	}
+
+	return checksum, manifest, nil
+}
+
+// Diff returns the names of the files that were added, removed, and modified in
+// got compared to want. Every list is sorted.
+func Diff(want, got Manifest) (added, removed, modified []string) {
+	for name, hash := range got {
+		if wantHash, ok := want[name]; !ok {
+			added = append(added, name)
+		} else if hash != wantHash {
+			modified = append(modified, name)
+		}
+	}
+
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(modified)
+	return added, removed, modified
+}
+
+// Parse returns the algorithm that produced the given checksum, based on its
+// tag. The checksum must have the form "<tag>:<digest>".
+func Parse(checksum string) (Algo, error) {
+	tag, _, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return 0, fmt.Errorf("%v: missing tag", ErrUnknownAlgo)
+	}
+
+	for algo, candidate := range tags {
+		if tag == candidate {
+			return algo, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%v: %q", ErrUnknownAlgo, tag)
+}
+
+// ParseName returns the algorithm with the given human readable name, for
+// example "sha256".
+func ParseName(name string) (Algo, error) {
+	for algo, candidate := range names {
+		if name == candidate {
+			return algo, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%v: %q", ErrUnknownAlgo, name)
+}
+
+// String returns the human readable name of the algorithm.
+func (algo Algo) String() string {
+	return names[algo]
+}
+
+// Tag returns the tag identifying checksums produced by the algorithm.
+func (algo Algo) Tag() string {
+	return tags[algo]
+}
+
+// checkLimits returns an error if the given entries exceed the given limits.
+func checkLimits(limits Limits, entries map[string]fs.DirEntry) error {
+	if limits.Files > 0 && len(entries) > limits.Files {
+		return fmt.Errorf("more than %d files", limits.Files)
+	}
+
+	if limits.Size <= 0 {
+		return nil
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if size += info.Size(); size > limits.Size {
+			return fmt.Errorf("files larger than %d bytes in total", limits.Size)
+		}
+	}
+
+	return nil
+}
+
+// contained reports whether the symbolic link with the given name resolves to a
+// path within the file system hierarchy, given the targets of all symbolic
+// links in the hierarchy. Absolute targets are never contained, nor are links
+// that require following too many other links.
+func contained(name string, links map[string]string) bool {
+	hops := 0
+
+	var resolve func(dir []string, target string) ([]string, bool)
+	resolve = func(dir []string, target string) ([]string, bool) {
+		if path.IsAbs(filepath.ToSlash(target)) || filepath.IsAbs(target) {
+			return nil, false
+		}
+
+		current := slices.Clone(dir)
+		for _, part := range strings.Split(target, "/") {
+			switch part {
+			case "", ".":
+				continue
+			case "..":
+				if len(current) == 0 {
+					return nil, false
+				}
+
+				current = current[:len(current)-1]
+			default:
+				current = append(current, part)
+
+				link, ok := links[strings.Join(current, "/")]
+				if !ok {
+					continue
+				}
+
+				if hops++; hops > maxLinks {
+					return nil, false
+				}
+
+				if current, ok = resolve(current[:len(current)-1], link); !ok {
+					return nil, false
+				}
+			}
+		}
+
+		return current, true
+	}
+
+	var dir []string
+	if parent := path.Dir(name); parent != "." {
+		dir = strings.Split(parent, "/")
+	}
+
+	_, ok := resolve(dir, links[name])
+	return ok
+}
+
+// hashContent returns a function that describes a file by the hex-encoded hash
+// of its content.
+func hashContent(newHash func() hash.Hash, open func(string) (io.ReadCloser, error)) func(string) (string, error) {
+	return func(name string) (string, error) {
+		r, err := open(name)
+		if err != nil {
+			return "", err
+		}
+
+		defer r.Close()
+
+		h := newHash()
+		if _, err := io.Copy(h, r); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%x", h.Sum(nil)), nil
+	}
+}
+
+// hashEntry returns a function that describes a file by its mode and the
+// hex-encoded hash of its content. Symbolic links are not followed, instead
+// they are described by the hash of their target.
+func hashEntry(newHash func() hash.Hash, files fs.FS, entries map[string]fs.DirEntry, links map[string]string) func(string) (string, error) {
+	content := hashContent(newHash, func(name string) (io.ReadCloser, error) {
+		return files.Open(name)
+	})
+
+	return func(name string) (string, error) {
+		if target, ok := links[name]; ok {
+			h := newHash()
+			_, _ = io.WriteString(h, target)
+			return fmt.Sprintf("%s %x", modeSymlink, h.Sum(nil)), nil
+		}
+
+		info, err := entries[name].Info()
+		if err != nil {
+			return "", err
+		}
+
+		mode := modeFile
+		if info.Mode()&0o111 != 0 {
+			mode = modeExecutable
+		}
+
+		digest, err := content(name)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s %s", mode, digest), nil
+	}
+}
+
+// readLinks returns the targets of all symbolic links among the given entries.
+func readLinks(files fs.FS, entries map[string]fs.DirEntry) (map[string]string, error) {
+	links := make(map[string]string)
+	for name, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		fsys, ok := files.(readLinkFS)
+		if !ok {
+			return nil, fmt.Errorf("cannot read symbolic link %q", name)
+		}
+
+		target, err := fsys.ReadLink(name)
+		if err != nil {
+			return nil, err
+		}
+
+		links[name] = target
+	}
+
+	return links, nil
+}
+
+// summarize computes a directory hash in the style of dirhash.Hash1 using the
+// given hash function, identified by the given tag.
+//
+// It hashes a summary of the files, listing the description and name of every
+// file sorted by name, and encodes the result as "<tag>:<base64>". The
+// descriptions of the files are returned as a manifest.
+func summarize(tag string, newHash func() hash.Hash, files []string, describe func(string) (string, error)) (string, Manifest, error) {
+	files = slices.Clone(files)
+	slices.Sort(files)
+
+	manifest := make(Manifest, len(files))
+	summary := newHash()
+	for _, file := range files {
+		if strings.Contains(file, "\n") {
+			return "", nil, errors.New("filenames with newlines are not supported")
+		}
+
+		description, err := describe(file)
+		if err != nil {
+			return "", nil, err
+		}
+
+		manifest[file] = description
+		fmt.Fprintf(summary, "%s  %s\n", manifest[file], file)
 	}
 
-	return checksum, nil
+	return tag + ":" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), manifest, nil
 }
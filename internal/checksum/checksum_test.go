@@ -15,7 +15,18 @@
 package checksum
 
 import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/ericcornelissen/ghasum/internal/vfs"
+	"golang.org/x/mod/sumdb/dirhash"
 )
 
 func TestExitCodes(t *testing.T) {
@@ -23,6 +34,9 @@ func TestExitCodes(t *testing.T) {
 
 	algos := []Algo{
 		Sha256,
+		Sha512,
+		Blake3,
+		Sha256Modes,
 		BestAlgo,
 	}
 
@@ -30,5 +44,339 @@ func TestExitCodes(t *testing.T) {
 		if _, ok := hashes[algo]; !ok {
 			t.Errorf("Missing algorithm %d from the hashes map", algo)
 		}
+
+		if _, ok := tags[algo]; !ok {
+			t.Errorf("Missing algorithm %d from the tags map", algo)
+		}
+
+		if got, err := ParseName(algo.String()); err != nil || got != algo {
+			t.Errorf("Could not parse the name of algorithm %d", algo)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	t.Parallel()
+
+	files := fstest.MapFS{
+		"action.yml":      {Data: []byte("name: example")},
+		"src/index.js":    {Data: []byte("console.log('Hello world!');")},
+		"src/lib/util.js": {Data: []byte("module.exports = {};")},
+	}
+
+	dir := t.TempDir()
+	for name, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Could not create directory for %q: %v", name, err)
+		}
+
+		if err := os.WriteFile(path, file.Data, 0o644); err != nil {
+			t.Fatalf("Could not write %q: %v", name, err)
+		}
+	}
+
+	want, err := dirhash.HashDir(dir, "", dirhash.Hash1)
+	if err != nil {
+		t.Fatalf("Could not compute reference checksum: %v", err)
+	}
+
+	got, err := Compute(files, Sha256)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("Incorrect checksum (got %q, want %q)", got, want)
+	}
+
+	if _, err := Compute(os.DirFS(filepath.Join(dir, "missing")), Sha256); err == nil {
+		t.Error("Unexpected success for missing directory")
+	}
+}
+
+func TestComputeModes(t *testing.T) {
+	t.Parallel()
+
+	// Every variant has the same file names and contents, but different modes or
+	// symbolic link targets.
+	variants := map[string]func(files *vfs.FS) error{
+		"regular file": func(files *vfs.FS) error {
+			return files.WriteFile("run.sh", []byte("echo hello"), 0o644)
+		},
+		"executable file": func(files *vfs.FS) error {
+			return files.WriteFile("run.sh", []byte("echo hello"), 0o755)
+		},
+		"symlink": func(files *vfs.FS) error {
+			return files.Symlink("hello.sh", "run.sh")
+		},
+		"symlink elsewhere": func(files *vfs.FS) error {
+			return files.Symlink("bin/hello.sh", "run.sh")
+		},
+	}
+
+	h1 := make(map[string]string, len(variants))
+	h2 := make(map[string]string, len(variants))
+	for name, setup := range variants {
+		files := vfs.New()
+		if err := files.WriteFile("hello.sh", []byte("echo hello"), 0o644); err != nil {
+			t.Fatalf("Could not set up %q: %v", name, err)
+		}
+
+		if err := files.WriteFile("bin/hello.sh", []byte("echo hello"), 0o644); err != nil {
+			t.Fatalf("Could not set up %q: %v", name, err)
+		}
+
+		if err := setup(files); err != nil {
+			t.Fatalf("Could not set up %q: %v", name, err)
+		}
+
+		var err error
+		if h1[name], err = Compute(files, Sha256); err != nil {
+			t.Fatalf("Unexpected error for %q: %v", name, err)
+		}
+
+		if h2[name], err = Compute(files, Sha256Modes); err != nil {
+			t.Fatalf("Unexpected error for %q: %v", name, err)
+		}
+	}
+
+	for name := range variants {
+		for other := range variants {
+			if name == other {
+				continue
+			}
+
+			if h1[name] != h1[other] {
+				t.Errorf("Unexpected h1 difference between %q and %q", name, other)
+			}
+
+			if h2[name] == h2[other] {
+				t.Errorf("Missing h2 difference between %q and %q", name, other)
+			}
+		}
+	}
+}
+
+func TestComputeSymlinks(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		links map[string]string
+		safe  bool
+	}
+
+	testCases := map[string]testCase{
+		"sibling": {
+			links: map[string]string{"run.sh": "hello.sh"},
+			safe:  true,
+		},
+		"nested": {
+			links: map[string]string{"run.sh": "bin/hello.sh"},
+			safe:  true,
+		},
+		"parent within the tree": {
+			links: map[string]string{"bin/run.sh": "../hello.sh"},
+			safe:  true,
+		},
+		"through another link": {
+			links: map[string]string{"link.sh": "bin/hello.sh", "run.sh": "link.sh"},
+			safe:  true,
+		},
+		"absolute": {
+			links: map[string]string{"run.sh": "/etc/passwd"},
+			safe:  false,
+		},
+		"parent outside the tree": {
+			links: map[string]string{"run.sh": "../hello.sh"},
+			safe:  false,
+		},
+		"nested parent outside the tree": {
+			links: map[string]string{"bin/run.sh": "../../hello.sh"},
+			safe:  false,
+		},
+		"outside the tree through another link": {
+			links: map[string]string{"self": ".", "run.sh": "self/../hello.sh"},
+			safe:  false,
+		},
+		"loop": {
+			links: map[string]string{"a": "b", "b": "a"},
+			safe:  false,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			files := vfs.New()
+			if err := files.WriteFile("hello.sh", []byte("echo hello"), 0o644); err != nil {
+				t.Fatalf("Could not set up files: %v", err)
+			}
+
+			if err := files.WriteFile("bin/hello.sh", []byte("echo hello"), 0o644); err != nil {
+				t.Fatalf("Could not set up files: %v", err)
+			}
+
+			for link, target := range tt.links {
+				if err := files.Symlink(target, link); err != nil {
+					t.Fatalf("Could not set up files: %v", err)
+				}
+			}
+
+			for _, algo := range []Algo{Sha256, Sha256Modes} {
+				_, err := Compute(files, algo)
+				if tt.safe && err != nil {
+					t.Errorf("Unexpected error for %q: %v", algo, err)
+				}
+
+				if !tt.safe && !errors.Is(err, ErrUnsafeSymlink) {
+					t.Errorf("Unexpected result for %q (got %v)", algo, err)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeLimits(t *testing.T) {
+	t.Parallel()
+
+	files := fstest.MapFS{
+		"action.yml":   {Data: []byte("name: example")},
+		"src/index.js": {Data: []byte("console.log('Hello world!');")},
+	}
+
+	type testCase struct {
+		limits Limits
+		ok     bool
+	}
+
+	testCases := map[string]testCase{
+		"no limits": {
+			limits: Limits{},
+			ok:     true,
+		},
+		"within limits": {
+			limits: Limits{Files: 2, Size: 41},
+			ok:     true,
+		},
+		"too many files": {
+			limits: Limits{Files: 1},
+			ok:     false,
+		},
+		"too large": {
+			limits: Limits{Size: 40},
+			ok:     false,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := ComputeManifest(files, Sha256, tt.limits)
+			if tt.ok && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !tt.ok && err == nil {
+				t.Error("Unexpected success")
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	files := fstest.MapFS{
+		"action.yml":   {Data: []byte("name: example")},
+		"src/index.js": {Data: []byte("console.log('Hello world!');")},
+	}
+
+	names := []string{"src/index.js", "action.yml"}
+	open := func(name string) (io.ReadCloser, error) {
+		return files.Open(name)
+	}
+
+	want, err := dirhash.Hash1(names, open)
+	if err != nil {
+		t.Fatalf("Could not compute reference checksum: %v", err)
+	}
+
+	got, _, err := summarize("h1", sha256.New, names, hashContent(sha256.New, open))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("Incorrect checksum (got %q, want %q)", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	files := fstest.MapFS{
+		"action.yml": {Data: []byte("name: example")},
+	}
+
+	for _, algo := range []Algo{Sha256, Sha512, Blake3, Sha256Modes} {
+		checksum, err := Compute(files, algo)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", algo.Tag(), err)
+		}
+
+		if !strings.HasPrefix(checksum, algo.Tag()+":") {
+			t.Errorf("Missing tag in %q (want %q)", checksum, algo.Tag())
+		}
+
+		got, err := Parse(checksum)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", checksum, err)
+		} else if got != algo {
+			t.Errorf("Incorrect algorithm for %q (got %d, want %d)", checksum, got, algo)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"Xl8z/l21IIpcBDsjpnq7jsBPk/RY26RwvDVL8FrajmE=",
+		"md5:Xl8z/l21IIpcBDsjpnq7jsBPk/RY26RwvDVL8FrajmE=",
+	}
+
+	for _, checksum := range invalid {
+		if _, err := Parse(checksum); err == nil {
+			t.Errorf("Unexpected success for %q", checksum)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	want := Manifest{
+		"action.yml":   "1",
+		"src/index.js": "2",
+		"src/util.js":  "3",
+	}
+
+	got := Manifest{
+		"action.yml":   "1",
+		"src/index.js": "4",
+		"src/new.js":   "5",
+	}
+
+	added, removed, modified := Diff(want, got)
+	if !slices.Equal(added, []string{"src/new.js"}) {
+		t.Errorf("Incorrect added files (got %v)", added)
+	}
+
+	if !slices.Equal(removed, []string{"src/util.js"}) {
+		t.Errorf("Incorrect removed files (got %v)", removed)
+	}
+
+	if !slices.Equal(modified, []string{"src/index.js"}) {
+		t.Errorf("Incorrect modified files (got %v)", modified)
 	}
 }
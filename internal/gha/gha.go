@@ -17,7 +17,9 @@ package gha
 import (
 	"fmt"
 	"io/fs"
+	"maps"
 	"path"
+	"slices"
 )
 
 // A GitHubAction identifies a specific version of a GitHub Action.
@@ -115,3 +117,35 @@ func JobActions(repo fs.FS, path, name string) ([]GitHubAction, error) {
 
 	return actions, nil
 }
+
+// Workflows returns the paths of the workflows in the repository at the given
+// file system hierarchy.
+func Workflows(repo fs.FS) ([]string, error) {
+	rawWorkflows, err := workflowsInRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(rawWorkflows))
+	for i, rawWorkflow := range rawWorkflows {
+		paths[i] = rawWorkflow.path
+	}
+
+	return paths, nil
+}
+
+// Jobs returns the ids of the jobs in the specified workflow at the given file
+// system hierarchy, in alphabetical order.
+func Jobs(repo fs.FS, path string) ([]string, error) {
+	data, err := workflowInRepo(repo, path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := parseWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Sorted(maps.Keys(w.Jobs)), nil
+}
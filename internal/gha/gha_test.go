@@ -372,3 +372,112 @@ func TestJobActions(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkflows(t *testing.T) {
+	t.Parallel()
+
+	workflows := map[string]mockFsEntry{
+		"nested": {
+			Dir: true,
+			Children: map[string]mockFsEntry{
+				"nested.yml": {
+					Content: []byte(workflowWithJobWithSteps),
+				},
+			},
+		},
+		"not-a-workflow.txt": {
+			Content: []byte("Hello world!"),
+		},
+		"one-job.yaml": {
+			Content: []byte(workflowWithJobWithSteps),
+		},
+		"multiple-jobs.yml": {
+			Content: []byte(workflowWithJobsWithSteps),
+		},
+	}
+
+	repo, err := mockRepo(workflows)
+	if err != nil {
+		t.Fatalf("Could not initialize file system: %+v", err)
+	}
+
+	got, err := Workflows(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	want := []string{
+		".github/workflows/multiple-jobs.yml",
+		".github/workflows/one-job.yaml",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Unexpected result (got %v, want %v)", got, want)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	t.Parallel()
+
+	type TestCase struct {
+		workflows map[string]mockFsEntry
+		workflow  string
+		want      []string
+		wantErr   bool
+	}
+
+	testCases := map[string]TestCase{
+		"no jobs": {
+			workflows: map[string]mockFsEntry{
+				"workflow.yml": {
+					Content: []byte(workflowWithNoJobs),
+				},
+			},
+			workflow: ".github/workflows/workflow.yml",
+			want:     []string{},
+		},
+		"multiple jobs": {
+			workflows: map[string]mockFsEntry{
+				"workflow.yml": {
+					Content: []byte(workflowWithJobsWithSteps),
+				},
+			},
+			workflow: ".github/workflows/workflow.yml",
+			want:     []string{"job-a", "job-b"},
+		},
+		"workflow not found": {
+			workflows: map[string]mockFsEntry{},
+			workflow:  ".github/workflows/workflow.yml",
+			wantErr:   true,
+		},
+		"syntax error": {
+			workflows: map[string]mockFsEntry{
+				"workflow.yml": {
+					Content: []byte(workflowWithSyntaxError),
+				},
+			},
+			workflow: ".github/workflows/workflow.yml",
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo, err := mockRepo(tt.workflows)
+			if err != nil {
+				t.Fatalf("Could not initialize file system: %+v", err)
+			}
+
+			got, err := Jobs(repo, tt.workflow)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Unexpected result (got %v, want %v)", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,13 +15,18 @@
 package ghasum
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
+	"slices"
 	"strings"
 
+	"github.com/ericcornelissen/ghasum/internal/cache"
 	"github.com/ericcornelissen/ghasum/internal/checksum"
 	"github.com/ericcornelissen/ghasum/internal/gha"
 	"github.com/ericcornelissen/ghasum/internal/github"
@@ -30,6 +35,18 @@ import (
 
 var ghasumPath = path.Join(gha.WorkflowsPath, "gha.sum")
 
+// generatorHeader is the name of the sumfile header recording the program that
+// wrote the sumfile.
+const generatorHeader = "generator"
+
+func cacheKey(entry sumfile.Entry) (string, error) {
+	if len(entry.ID) != 2 {
+		return "", fmt.Errorf("invalid id %q", strings.Join(entry.ID, "@"))
+	}
+
+	return path.Join(entry.ID[0], entry.ID[1]), nil
+}
+
 func clear(file *os.File) error {
 	if _, err := file.Seek(0, 0); err != nil {
 		return errors.Join(ErrSumfileWrite, err)
@@ -42,7 +59,9 @@ func clear(file *os.File) error {
 	return nil
 }
 
-func compare(got, want []sumfile.Entry) []Problem {
+// compare compares the computed checksums against the stored checksums. The
+// manifests, if available, are used to explain checksum mismatches.
+func compare(got, want []sumfile.Entry, gotFiles, wantFiles manifests) []Problem {
 	toMap := func(entries []sumfile.Entry) map[string]string {
 		m := make(map[string]string, len(entries))
 		for _, entry := range entries {
@@ -55,17 +74,26 @@ func compare(got, want []sumfile.Entry) []Problem {
 
 	cmp := func(got, want map[string]string) []Problem {
 		problems := make([]Problem, 0)
-		for key, got := range got {
+		for _, key := range slices.Sorted(maps.Keys(got)) {
 			want, ok := want[key]
 			if !ok {
-				p := fmt.Sprintf("no checksum found for %q", key)
-				problems = append(problems, Problem(p))
+				problems = append(problems, Problem{
+					Kind:   ProblemMissing,
+					ID:     key,
+					Actual: got[key],
+				})
 				continue
 			}
 
-			if got != want {
-				p := fmt.Sprintf("checksum mismatch for %q", key)
-				problems = append(problems, Problem(p))
+			if got[key] != want {
+				p := Problem{
+					Kind:     ProblemMismatch,
+					ID:       key,
+					Expected: want,
+					Actual:   got[key],
+				}
+				explain(&p, gotFiles, wantFiles)
+				problems = append(problems, p)
 			}
 		}
 
@@ -75,19 +103,21 @@ func compare(got, want []sumfile.Entry) []Problem {
 	return cmp(toMap(got), toMap(want))
 }
 
-func find(cfg *Config) ([]gha.GitHubAction, error) {
+// find finds the actions used in the given workflow, or in all workflows if the
+// workflow is empty. The Job of the config only applies to the given workflow.
+func find(cfg *Config, workflow string) ([]gha.GitHubAction, error) {
 	var (
 		actions []gha.GitHubAction
 		err     error
 	)
 
-	if cfg.Workflow == "" {
+	if workflow == "" {
 		actions, err = gha.RepoActions(cfg.Repo)
 	} else {
 		if cfg.Job == "" {
-			actions, err = gha.WorkflowActions(cfg.Repo, cfg.Workflow)
+			actions, err = gha.WorkflowActions(cfg.Repo, workflow)
 		} else {
-			actions, err = gha.JobActions(cfg.Repo, cfg.Workflow, cfg.Job)
+			actions, err = gha.JobActions(cfg.Repo, workflow, cfg.Job)
 		}
 	}
 
@@ -98,55 +128,147 @@ func find(cfg *Config) ([]gha.GitHubAction, error) {
 	return actions, nil
 }
 
-func compute(cfg *Config, actions []gha.GitHubAction, algo checksum.Algo) ([]sumfile.Entry, error) {
+// compute computes the checksums for the given actions, as well as manifests of
+// the files covered by them. An action is hashed using the algorithm of its
+// stored checksum if there is one, or using the given algorithm otherwise.
+//
+// Actions that cannot be fetched, or cannot be hashed safely because they
+// contain a symbolic link escaping the repository, are reported as problems and
+// omitted from the checksums. An action in quarantine in the cache is promoted
+// only if its checksum matches the stored checksum.
+//
+// Actions are fetched and hashed concurrently, but the result is ordered by
+// owner, project, and ref regardless. If computing the checksum of multiple
+// actions fails, the error for the first action in that order is returned.
+func compute(cfg *Config, actions []gha.GitHubAction, stored []sumfile.Entry, algo checksum.Algo) ([]sumfile.Entry, manifests, []Problem, error) {
+	actions = slices.SortedFunc(slices.Values(actions), func(a, b gha.GitHubAction) int {
+		return cmp.Or(
+			cmp.Compare(a.Owner, b.Owner),
+			cmp.Compare(a.Project, b.Project),
+			cmp.Compare(a.Ref, b.Ref),
+		)
+	})
+
+	algos := make(map[string]checksum.Algo, len(stored))
+	checksums := make(map[string]string, len(stored))
+	for _, entry := range stored {
+		entryAlgo, err := checksum.Parse(entry.Checksum)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid checksum for %q: %v", strings.Join(entry.ID, "@"), err)
+		}
+
+		algos[strings.Join(entry.ID, "@")] = entryAlgo
+		checksums[strings.Join(entry.ID, "@")] = entry.Checksum
+	}
+
 	if err := cfg.Cache.Init(); err != nil {
-		return nil, fmt.Errorf("could not initialize cache: %v", err)
+		return nil, nil, nil, fmt.Errorf("could not initialize cache: %v", err)
 	} else {
 		defer cfg.Cache.Cleanup()
 	}
 
-	entries := make([]sumfile.Entry, len(actions))
-	for i, action := range actions {
+	type result struct {
+		entry    sumfile.Entry
+		manifest checksum.Manifest
+		problem  *Problem
+	}
+
+	// repositories serializes access to the git object store of every
+	// repository, which is shared by all refs of the repository and cannot be
+	// written to concurrently.
+	var repositories keyedMutex
+
+	results := make([]result, len(actions))
+	err := forEach(cfg.Jobs, len(actions), func(i int) error {
+		action := actions[i]
 		repo := github.Repository{
 			Owner:   action.Owner,
 			Project: action.Project,
 			Ref:     action.Ref,
 		}
 
-		actionDir := path.Join(cfg.Cache.Path(), repo.Owner, repo.Project, repo.Ref)
-		if _, err := os.Stat(actionDir); err != nil {
-			if cfg.Offline {
-				return nil, fmt.Errorf("missing %q from cache", actionDir)
+		id := []string{fmt.Sprintf("%s/%s", repo.Owner, repo.Project), action.Ref}
+
+		actionFiles, err := lookup(cfg, &repositories, &repo)
+		var fetchErr fetchError
+		if errors.As(err, &fetchErr) {
+			results[i].problem = &Problem{
+				Kind:   ProblemFetchFailed,
+				ID:     strings.Join(id, "@"),
+				Detail: strings.ReplaceAll(fetchErr.err.Error(), "\n", ": "),
 			}
+			return nil
+		} else if err != nil {
+			return err
+		}
 
-			err := github.Clone(actionDir, &repo)
-			if err != nil {
-				return nil, fmt.Errorf("clone failed: %v", err)
+		actionAlgo, ok := algos[strings.Join(id, "@")]
+		if !ok {
+			actionAlgo = algo
+		}
+
+		actionChecksum, actionManifest, err := checksum.ComputeManifest(actionFiles, actionAlgo, hashLimits(cfg))
+		if errors.Is(err, checksum.ErrUnsafeSymlink) {
+			detail := strings.ReplaceAll(err.Error(), "\n", ": ")
+			results[i].problem = &Problem{
+				Kind:   ProblemUnsafe,
+				ID:     strings.Join(id, "@"),
+				Detail: detail,
 			}
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not compute checksum for %q: %v", strings.Join(id, "@"), err)
 		}
 
-		// checksum, err := dirhash.HashDir(actionDir, "", hashes[algo])
-		checksum, err := checksum.Compute(actionDir, algo)
-		if err != nil {
-			return nil, fmt.Errorf("could not compute checksum for %q: %v", action, err)
+		if checksums[strings.Join(id, "@")] == actionChecksum {
+			if err := promote(cfg, &repositories, &repo); err != nil {
+				return err
+			}
 		}
 
-		entries[i] = sumfile.Entry{
-			ID:       []string{fmt.Sprintf("%s/%s", repo.Owner, repo.Project), action.Ref},
-			Checksum: strings.Replace(checksum, "h1:", "", 1),
+		results[i].entry = sumfile.Entry{
+			ID:       id,
+			Checksum: actionChecksum,
+		}
+		results[i].manifest = actionManifest
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	entries := make([]sumfile.Entry, 0, len(actions))
+	files := make(manifests, len(actions))
+	problems := make([]Problem, 0)
+	for _, r := range results {
+		if r.problem != nil {
+			problems = append(problems, *r.problem)
+			continue
+		}
+
+		entries = append(entries, r.entry)
+		files[strings.Join(r.entry.ID, "@")] = manifest{
+			checksum: r.entry.Checksum,
+			files:    r.manifest,
 		}
 	}
 
-	return entries, nil
+	return entries, files, problems, nil
 }
 
-func create(base string) (*os.File, error) {
-	fullGhasumPath := path.Join(base, ghasumPath)
+func create(base, name string) (*os.File, error) {
+	fullGhasumPath := path.Join(base, name)
 
 	if _, err := os.Stat(fullGhasumPath); err == nil {
 		return nil, ErrInitialized
 	}
 
+	if err := os.MkdirAll(path.Dir(fullGhasumPath), 0o755); err != nil {
+		return nil, errors.Join(ErrSumfileCreate, err)
+	}
+
 	file, err := os.OpenFile(fullGhasumPath, os.O_CREATE|os.O_WRONLY, os.ModeExclusive)
 	if err != nil {
 		return nil, errors.Join(ErrSumfileCreate, err)
@@ -155,17 +277,57 @@ func create(base string) (*os.File, error) {
 	return file, nil
 }
 
-func decode(stored []byte) ([]sumfile.Entry, error) {
-	checksums, err := sumfile.Decode(string(stored))
+func decode(file string, stored []byte) ([]sumfile.Entry, error) {
+	doc, err := decodeDocument(file, stored)
+	return doc.Entries, err
+}
+
+// decodeDocument decodes the given checksum file content, where problems are
+// reported at their position in the given file if it is not empty.
+func decodeDocument(file string, stored []byte) (sumfile.Document, error) {
+	doc, err := sumfile.DecodeDocument(string(stored))
+	for i, entry := range doc.Entries {
+		if !strings.Contains(entry.Checksum, ":") {
+			doc.Entries[i].Checksum = fmt.Sprintf("%s:%s", checksum.Sha256.Tag(), entry.Checksum)
+		}
+	}
+
 	if err != nil {
-		return nil, errors.Join(ErrSumfileDecode, err)
+		var errs sumfile.ErrorList
+		if errors.As(err, &errs) && file != "" {
+			errs.SetFile(file)
+		}
+
+		return doc, errors.Join(ErrSumfileDecode, err)
 	}
 
-	return checksums, nil
+	return doc, nil
 }
 
-func encode(version sumfile.Version, checksums []sumfile.Entry) (string, error) {
-	content, err := sumfile.Encode(version, checksums)
+// encode encodes the given document, recording the given generator in the
+// generator header unless it is empty. An inline signature is dropped because
+// it does not cover the new content.
+func encode(doc sumfile.Document, generator string) (string, error) {
+	if doc.Version == sumfile.Version1 {
+		implicit := checksum.Sha256.Tag() + ":"
+
+		doc.Entries = slices.Clone(doc.Entries)
+		for i, entry := range doc.Entries {
+			doc.Entries[i].Checksum = strings.TrimPrefix(entry.Checksum, implicit)
+		}
+	}
+
+	doc.Headers = maps.Clone(doc.Headers)
+	delete(doc.Headers, signatureHeader)
+	if generator != "" {
+		if doc.Headers == nil {
+			doc.Headers = make(map[string]string, 1)
+		}
+
+		doc.Headers[generatorHeader] = generator
+	}
+
+	content, err := sumfile.EncodeDocument(doc)
 	if err != nil {
 		return "", errors.Join(ErrSumfileEncode, err)
 	}
@@ -173,8 +335,197 @@ func encode(version sumfile.Version, checksums []sumfile.Entry) (string, error)
 	return content, nil
 }
 
-func open(base string) (*os.File, error) {
-	fullGhasumPath := path.Join(base, ghasumPath)
+func fetch(cfg *Config, repo *github.Repository) (fs.FS, error) {
+	key := path.Join(repo.Owner, repo.Project, repo.Ref)
+
+	objects, err := cfg.Cache.Objects(path.Join(repo.Owner, repo.Project))
+	if err != nil {
+		return nil, fmt.Errorf("could not open object store: %v", err)
+	}
+
+	files, err := github.Fetch(objects, repo, cfg.Limits)
+	if err != nil {
+		return nil, fetchError{err: err}
+	}
+
+	if err := cfg.Cache.Store(key, files); err != nil {
+		return nil, fmt.Errorf("could not store %q in cache: %v", key, err)
+	}
+
+	return cfg.Cache.Lookup(key)
+}
+
+// hashLimits returns the limits that apply to hashing the repository of any
+// single action.
+func hashLimits(cfg *Config) checksum.Limits {
+	return checksum.Limits{
+		Files: cfg.Limits.Files,
+		Size:  cfg.Limits.Size,
+	}
+}
+
+// initialize creates the checksum file with the given name, containing the
+// given headers and the checksums for the given actions. It returns manifests
+// of the files covered by the checksums.
+func initialize(cfg *Config, name string, headers map[string]string, actions []gha.GitHubAction) (manifests, error) {
+	file, err := create(cfg.Path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		deinitialize := (err != nil)
+		if err = file.Close(); err != nil || deinitialize {
+			_ = remove(cfg.Path, name)
+		}
+	}()
+
+	checksums, files, problems, err := compute(cfg, actions, nil, cfg.Algo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, problem := range problems {
+		err = errors.Join(err, errors.New(problem.String()))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc := sumfile.Document{
+		Version: sumfile.VersionDefault,
+		Headers: headers,
+		Entries: checksums,
+	}
+
+	content, err := encode(doc, cfg.Generator)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = write(file, content); err != nil {
+		return nil, err
+	}
+
+	if err = unlock(cfg.Path, name); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// lookup returns the files of the given repository from the cache, fetching
+// them if necessary. Lookups for the same repository (at any ref) are performed
+// one at a time, using the given locks, because they share the object store of
+// the repository.
+func lookup(cfg *Config, repositories *keyedMutex, repo *github.Repository) (fs.FS, error) {
+	unlock := repositories.lock(path.Join(repo.Owner, repo.Project))
+	defer unlock()
+
+	key := path.Join(repo.Owner, repo.Project, repo.Ref)
+
+	files, err := cfg.Cache.Lookup(key)
+	if err == nil {
+		return files, nil
+	} else if !errors.Is(err, cache.ErrMissing) {
+		return nil, fmt.Errorf("could not read %q from cache: %v", key, err)
+	}
+
+	if cfg.Offline {
+		return nil, fmt.Errorf("missing %q from cache", key)
+	}
+
+	return fetch(cfg, repo)
+}
+
+// migrate prepares the change of a checksum file to the given sumfile version
+// and hashing algorithm. Every stored checksum is verified first, if any does
+// not match the problems are returned and the change is left empty. Checksums
+// already computed with the given algorithm are not computed again.
+func migrate(cfg *Config, change *pending, version sumfile.Version, algo checksum.Algo, storedFiles manifests) (manifests, []Problem, error) {
+	raw, err := io.ReadAll(change.file)
+	if err != nil {
+		return nil, nil, errors.Join(ErrSumfileRead, err)
+	}
+
+	doc, err := decodeDocument(path.Join(cfg.Path, change.name), raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := doc.Entries
+
+	actions := make([]gha.GitHubAction, len(stored))
+	byID := make(map[string]gha.GitHubAction, len(stored))
+	for i, entry := range stored {
+		if len(entry.ID) != 2 || strings.Count(entry.ID[0], "/") != 1 {
+			return nil, nil, fmt.Errorf("invalid id %q", strings.Join(entry.ID, "@"))
+		}
+
+		owner, project, _ := strings.Cut(entry.ID[0], "/")
+		actions[i] = gha.GitHubAction{
+			Owner:   owner,
+			Project: project,
+			Ref:     entry.ID[1],
+		}
+		byID[strings.Join(entry.ID, "@")] = actions[i]
+	}
+
+	fresh, freshFiles, problems, err := compute(cfg, actions, stored, algo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	problems = append(problems, compare(fresh, stored, freshFiles, storedFiles)...)
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	checksums := make(map[string]string, len(fresh))
+	files := make(manifests, len(fresh))
+	outdated := make([]gha.GitHubAction, 0)
+	for _, entry := range fresh {
+		id := strings.Join(entry.ID, "@")
+		if entryAlgo, _ := checksum.Parse(entry.Checksum); entryAlgo == algo {
+			checksums[id], files[id] = entry.Checksum, freshFiles[id]
+		} else {
+			outdated = append(outdated, byID[id])
+		}
+	}
+
+	rehashed, rehashedFiles, problems, err := compute(cfg, outdated, nil, algo)
+	if err != nil {
+		return nil, nil, err
+	} else if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	for _, entry := range rehashed {
+		id := strings.Join(entry.ID, "@")
+		checksums[id], files[id] = entry.Checksum, rehashedFiles[id]
+	}
+
+	entries := make([]sumfile.Entry, len(stored))
+	for i, entry := range stored {
+		entries[i] = sumfile.Entry{
+			ID:         entry.ID,
+			Checksum:   checksums[strings.Join(entry.ID, "@")],
+			Attributes: entry.Attributes,
+			Comments:   entry.Comments,
+		}
+	}
+
+	doc.Version, doc.Entries = version, entries
+	if change.content, err = encode(doc, cfg.Generator); err != nil {
+		return nil, nil, err
+	}
+
+	return files, nil, nil
+}
+
+func open(base, name string) (*os.File, error) {
+	fullGhasumPath := path.Join(base, name)
 
 	file, err := os.OpenFile(fullGhasumPath, os.O_RDWR, os.ModeExclusive)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -190,8 +541,29 @@ func open(base string) (*os.File, error) {
 	return file, nil
 }
 
-func read(repo fs.FS) ([]byte, error) {
-	raw, err := fs.ReadFile(repo, ghasumPath)
+// promote promotes the given repository if it is in quarantine in the cache. It
+// must only be called once the files of the repository are found to match their
+// stored checksum. The given locks are used to serialize access to the cache
+// entries of the repository.
+func promote(cfg *Config, repositories *keyedMutex, repo *github.Repository) error {
+	quarantine, ok := cfg.Cache.(cache.Quarantine)
+	if !ok {
+		return nil
+	}
+
+	unlock := repositories.lock(path.Join(repo.Owner, repo.Project))
+	defer unlock()
+
+	key := path.Join(repo.Owner, repo.Project, repo.Ref)
+	if err := quarantine.Promote(key); err != nil {
+		return fmt.Errorf("could not store %q in cache: %v", key, err)
+	}
+
+	return nil
+}
+
+func read(repo fs.FS, name string) ([]byte, error) {
+	raw, err := fs.ReadFile(repo, name)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, ErrNotInitialized
 	} else if err != nil {
@@ -201,8 +573,8 @@ func read(repo fs.FS) ([]byte, error) {
 	return raw, nil
 }
 
-func remove(base string) error {
-	fullGhasumPath := path.Join(base, ghasumPath)
+func remove(base, name string) error {
+	fullGhasumPath := path.Join(base, name)
 	if err := os.Remove(fullGhasumPath); err != nil {
 		return errors.Join(ErrSumfileRemove, err)
 	}
@@ -210,8 +582,8 @@ func remove(base string) error {
 	return nil
 }
 
-func unlock(base string) error {
-	fullGhasumPath := path.Join(base, ghasumPath)
+func unlock(base, name string) error {
+	fullGhasumPath := path.Join(base, name)
 	if err := os.Chmod(fullGhasumPath, fs.ModePerm); err != nil {
 		return errors.Join(ErrSumfileUnlock, err)
 	}
@@ -219,13 +591,63 @@ func unlock(base string) error {
 	return nil
 }
 
-func version(stored []byte) (sumfile.Version, error) {
-	version, err := sumfile.DecodeVersion(string(stored))
+// update prepares the change of a checksum file, with the given raw content or
+// nil if it does not exist, for the current actions of its workflow, preserving
+// existing checksums. It returns the new checksums, unless any action cannot be
+// hashed safely in which case the problems are returned.
+func update(cfg *Config, change *pending, raw []byte, force bool) ([]sumfile.Entry, manifests, []Problem, error) {
+	doc := sumfile.Document{Version: sumfile.VersionDefault}
+	if raw != nil {
+		var err error
+		doc, err = decodeDocument(path.Join(cfg.Path, change.name), raw)
+		if err != nil {
+			if !force {
+				return nil, nil, nil, errors.Join(ErrSumfileRead, err)
+			}
+
+			if errors.Is(err, sumfile.ErrHeaders) || errors.Is(err, sumfile.ErrVersion) {
+				doc.Version, doc.Headers = sumfile.VersionDefault, nil
+			}
+		}
+	}
+
+	oldChecksums := doc.Entries
+
+	actions, err := find(cfg, change.workflow)
 	if err != nil {
-		return version, errors.Join(ErrSumfileDecode, err)
+		return nil, nil, nil, err
+	}
+
+	checksums, files, problems, err := compute(cfg, actions, nil, cfg.Algo)
+	if err != nil {
+		return nil, nil, nil, err
+	} else if len(problems) > 0 {
+		return nil, nil, problems, nil
+	}
+
+	for i, entry := range checksums {
+		for _, oldEntry := range oldChecksums {
+			if !slices.Equal(entry.ID, oldEntry.ID) {
+				continue
+			}
+
+			if force {
+				checksums[i].Attributes = oldEntry.Attributes
+				checksums[i].Comments = oldEntry.Comments
+			} else {
+				checksums[i] = oldEntry
+			}
+
+			break
+		}
+	}
+
+	doc.Entries = checksums
+	if change.content, err = encode(doc, cfg.Generator); err != nil {
+		return nil, nil, nil, err
 	}
 
-	return version, nil
+	return checksums, files, nil, nil
 }
 
 func write(file *os.File, content string) error {
@@ -0,0 +1,73 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+// Change represents a change to a single checksum in a checksum file.
+type Change struct {
+	// File is the path of the checksum file, relative to the repository.
+	File string
+
+	// ID is the identifier of the action, for example "actions/checkout@v4".
+	ID string
+
+	// Old is the stored checksum, or empty if the checksum is added.
+	Old string
+
+	// New is the checksum after the change, or empty if the checksum is
+	// removed.
+	New string
+}
+
+// diff returns the changes from the checksums before to the checksums after in
+// the checksum file with the given name, sorted by action.
+func diff(name string, before, after []sumfile.Entry) []Change {
+	index := func(entries []sumfile.Entry) map[string]string {
+		m := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			m[strings.Join(entry.ID, "@")] = entry.Checksum
+		}
+
+		return m
+	}
+
+	beforeIndex, afterIndex := index(before), index(after)
+
+	changes := make([]Change, 0)
+	for id, checksum := range beforeIndex {
+		if afterIndex[id] != checksum {
+			changes = append(changes, Change{File: name, ID: id, Old: checksum, New: afterIndex[id]})
+		}
+	}
+
+	for id, checksum := range afterIndex {
+		if _, ok := beforeIndex[id]; !ok {
+			changes = append(changes, Change{File: name, ID: id, New: checksum})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return changes
+}
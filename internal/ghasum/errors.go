@@ -14,7 +14,10 @@
 
 package ghasum
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrInitialized is the error used when ghasum is not expected to be
@@ -57,3 +60,13 @@ var (
 	// be written to.
 	ErrSumfileWrite = errors.New("could not write to the checksum file")
 )
+
+// A fetchError is the error used when the repository of an action could not be
+// fetched, as opposed to a failure of the cache.
+type fetchError struct {
+	err error
+}
+
+func (e fetchError) Error() string {
+	return fmt.Sprintf("fetch failed: %v", e.err)
+}
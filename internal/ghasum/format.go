@@ -0,0 +1,54 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import "strings"
+
+// canonical returns the canonical form of the given content of the given
+// checksum file, and whether it differs from the content. An inline signature
+// is kept only if the content is already canonical.
+//
+// Unlike regular decoding, trailing whitespace on any line and a missing final
+// newline are tolerated because they are common in hand-edited files.
+func canonical(file string, raw []byte) (string, bool, error) {
+	message, _, _ := splitSignature(raw)
+
+	doc, err := decodeDocument(file, []byte(tidy(string(message))))
+	if err != nil {
+		return "", false, err
+	}
+
+	content, err := encode(doc, "")
+	if err != nil {
+		return "", false, err
+	}
+
+	if content == string(message) {
+		return string(raw), false, nil
+	}
+
+	return content, true, nil
+}
+
+// tidy removes trailing whitespace from every line of the given content and
+// ensures it ends with a newline.
+func tidy(content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,299 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/gha"
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+const (
+	// layoutHeader is the name of the sumfile header recording the layout of
+	// the checksum files.
+	layoutHeader = "layout"
+
+	// layoutSplit is the layout in which every workflow has a checksum file of
+	// its own, next to the checksum file recording the layout.
+	layoutSplit = "split"
+
+	// sumfileHeader is the name of the sumfile header, in the checksum file at
+	// the default path, that points to the checksum file at another path.
+	sumfileHeader = "sumfile"
+)
+
+// A unit is a checksum file together with the workflow it covers. A unit that
+// covers all workflows has an empty workflow.
+type unit struct {
+	// name is the path of the checksum file relative to the repository.
+	name string
+
+	// workflow is the path of the workflow relative to the repository.
+	workflow string
+
+	// optional is set if the checksum file may be missing, in which case it is
+	// treated as a checksum file without entries.
+	optional bool
+}
+
+// A pending change is the new content for the checksum file of a unit, which is
+// only written once all units have been processed.
+type pending struct {
+	unit
+
+	// file is the opened checksum file, or nil if it does not exist yet.
+	file *os.File
+
+	// content is the new content of the checksum file.
+	content string
+}
+
+// allEntries returns the stored checksums of all checksum files for the given
+// configuration.
+func allEntries(cfg *Config) ([]sumfile.Entry, error) {
+	root, err := locate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := units(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]sumfile.Entry, 0)
+	for _, u := range targets {
+		stored, err := readUnit(cfg, u)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, stored...)
+	}
+
+	return all, nil
+}
+
+// commit writes the content of all given changes, creating the checksum files
+// that do not exist yet.
+func commit(cfg *Config, changes []pending) error {
+	for i, change := range changes {
+		if change.file == nil {
+			file, err := create(cfg.Path, change.name)
+			if err != nil {
+				return err
+			}
+
+			changes[i].file = file
+		} else if err := clear(change.file); err != nil {
+			return err
+		}
+
+		if err := write(changes[i].file, change.content); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		if err := unlock(cfg.Path, change.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headers returns the headers of the checksum file with the given name. If the
+// headers cannot be read the result is empty.
+func headers(repo fs.FS, name string) map[string]string {
+	raw, err := fs.ReadFile(repo, name)
+	if err != nil {
+		return nil
+	}
+
+	doc, _ := sumfile.DecodeDocument(string(raw))
+	return doc.Headers
+}
+
+// isPointer reports whether the checksum file with the given name only points
+// to other checksum files, either with the sumfile header or by recording the
+// split layout.
+func isPointer(repo fs.FS, name string) bool {
+	h := headers(repo, name)
+	return h[sumfileHeader] != "" || h[layoutHeader] == layoutSplit
+}
+
+// isSplit reports whether the checksum file with the given name records the
+// split layout.
+func isSplit(repo fs.FS, name string) bool {
+	return headers(repo, name)[layoutHeader] == layoutSplit
+}
+
+// isWorkflowSumfile reports whether the file with the given name could be the
+// checksum file of a workflow in the split layout.
+func isWorkflowSumfile(name string) bool {
+	workflow := strings.TrimSuffix(name, ".sum")
+	return workflow != name && (path.Ext(workflow) == ".yml" || path.Ext(workflow) == ".yaml")
+}
+
+// locate returns the path of the checksum file, relative to the repository,
+// for the given configuration. Unless the configuration specifies a path the
+// checksum file at the default path is used, or the checksum file it points to
+// with the sumfile header.
+func locate(cfg *Config) (string, error) {
+	name := cfg.Sumfile
+	if name == "" {
+		name = headers(cfg.Repo, ghasumPath)[sumfileHeader]
+		if name == "" {
+			return ghasumPath, nil
+		}
+	}
+
+	name = path.Clean(name)
+	if !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("invalid checksum file path %q", name)
+	}
+
+	return name, nil
+}
+
+// orphans returns the checksum files of workflows, next to the checksum file at
+// root, that do not belong to any of the given units.
+func orphans(cfg *Config, root string, targets []unit) ([]string, error) {
+	dir := path.Dir(root)
+	files, err := fs.ReadDir(cfg.Repo, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, file := range files {
+		name := path.Join(dir, file.Name())
+		if file.IsDir() || !isWorkflowSumfile(name) {
+			continue
+		}
+
+		used := func(u unit) bool { return u.name == name }
+		if !slices.ContainsFunc(targets, used) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// prune removes the checksum files of workflows, next to the checksum file at
+// root, that do not belong to any of the given units.
+func prune(cfg *Config, root string, targets []unit) error {
+	names, err := orphans(cfg, root, targets)
+	if err != nil {
+		return errors.Join(ErrSumfileRemove, err)
+	}
+
+	for _, name := range names {
+		if err := remove(cfg.Path, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readUnit(cfg *Config, u unit) ([]sumfile.Entry, error) {
+	raw, err := read(cfg.Repo, u.name)
+	if u.optional && errors.Is(err, ErrNotInitialized) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decode(path.Join(cfg.Path, u.name), raw)
+}
+
+// release unlocks and closes the checksum files of all given changes.
+func release(cfg *Config, changes []pending) {
+	for _, change := range changes {
+		if change.file != nil {
+			_ = unlock(cfg.Path, change.name)
+			_ = change.file.Close()
+		}
+	}
+}
+
+// sumfiles returns the names of all checksum files for the given configuration,
+// given the path of its checksum file and its units. This includes the checksum
+// file at the default path if it points to the checksum file, as well as the
+// checksum files of units that exist.
+func sumfiles(cfg *Config, root string, targets []unit) []string {
+	names := make([]string, 0, len(targets)+2)
+	if cfg.Sumfile == "" && root != ghasumPath {
+		names = append(names, ghasumPath)
+	}
+
+	names = append(names, root)
+	for _, u := range targets {
+		if u.name == root {
+			continue
+		}
+
+		if _, err := fs.Stat(cfg.Repo, u.name); err == nil {
+			names = append(names, u.name)
+		}
+	}
+
+	return names
+}
+
+// units returns the units for the given configuration, given the path of its
+// checksum file. In the split layout there is a unit for every workflow (or
+// only for the workflow of the configuration, if any), otherwise the checksum
+// file is the only unit.
+func units(cfg *Config, root string) ([]unit, error) {
+	if !isSplit(cfg.Repo, root) {
+		return []unit{{name: root, workflow: cfg.Workflow}}, nil
+	}
+
+	workflows := []string{cfg.Workflow}
+	if cfg.Workflow == "" {
+		var err error
+		if workflows, err = gha.Workflows(cfg.Repo); err != nil {
+			return nil, fmt.Errorf("could not get workflows: %v", err)
+		}
+	}
+
+	result := make([]unit, len(workflows))
+	for i, workflow := range workflows {
+		result[i] = unit{
+			name:     workflowSumfile(root, workflow),
+			workflow: workflow,
+			optional: true,
+		}
+	}
+
+	return result, nil
+}
+
+// workflowSumfile returns the path of the checksum file for the given workflow
+// in the split layout, next to the checksum file at root.
+func workflowSumfile(root, workflow string) string {
+	return path.Join(path.Dir(root), path.Base(workflow)+".sum")
+}
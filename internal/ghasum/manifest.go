@@ -0,0 +1,184 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/checksum"
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+type (
+	// manifests are the manifests of the files covered by checksums, by the id
+	// of the checksum's entry.
+	manifests map[string]manifest
+
+	// manifest is the manifest of the files covered by a specific checksum.
+	manifest struct {
+		checksum string
+		files    checksum.Manifest
+	}
+)
+
+func decodeManifests(raw []byte) (manifests, error) {
+	lines := strings.Split(string(raw), "\n")
+	if len(lines) < 3 || lines[0] != "version 1" || lines[1] != "" {
+		return nil, errors.New("invalid manifest file header")
+	}
+
+	if lines[len(lines)-1] != "" {
+		return nil, errors.New("missing final newline")
+	}
+
+	m := make(manifests, 0)
+
+	var current *manifest
+	for i, line := range lines[2 : len(lines)-1] {
+		if line == "" {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			id, sum, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("syntax error on line %d", i+3)
+			}
+
+			current = &manifest{checksum: sum, files: make(checksum.Manifest, 0)}
+			m[id] = *current
+			continue
+		}
+
+		hash, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("syntax error on line %d", i+3)
+		}
+
+		current.files[name] = hash
+	}
+
+	return m, nil
+}
+
+func encodeManifests(m manifests) string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	var sb strings.Builder
+	sb.WriteString("version 1\n")
+	for _, id := range ids {
+		sb.WriteString(fmt.Sprintf("\n%s %s\n", id, m[id].checksum))
+
+		names := make([]string, 0, len(m[id].files))
+		for name := range m[id].files {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("%s  %s\n", m[id].files[name], name))
+		}
+	}
+
+	return sb.String()
+}
+
+// explain records on the given checksum mismatch how the files covered by the
+// checksum of its action differ between got and wanted. Nothing is recorded if
+// the manifest for wanted does not belong to the expected checksum.
+func explain(p *Problem, got, wanted manifests) {
+	g, ok := got[p.ID]
+	if !ok {
+		return
+	}
+
+	w, ok := wanted[p.ID]
+	if !ok || w.checksum != p.Expected {
+		return
+	}
+
+	p.Added, p.Removed, p.Modified = checksum.Diff(w.files, g.files)
+}
+
+// initializeManifests writes the manifest file for the checksum file with the
+// given name, if the configuration asks for it.
+func initializeManifests(cfg *Config, name string, files manifests) error {
+	if !cfg.Manifest {
+		return nil
+	}
+
+	return writeManifests(cfg.Path, name, files)
+}
+
+// manifestPath returns the path of the manifest file for the checksum file at
+// the given path.
+func manifestPath(name string) string {
+	return name + ".manifest"
+}
+
+// matching returns, for every entry, the first of the given manifests that
+// belongs to the entry's checksum. Entries without one are omitted.
+func matching(entries []sumfile.Entry, candidates ...manifests) manifests {
+	m := make(manifests, len(entries))
+	for _, entry := range entries {
+		id := strings.Join(entry.ID, "@")
+		for _, candidate := range candidates {
+			if c, ok := candidate[id]; ok && c.checksum == entry.Checksum {
+				m[id] = c
+				break
+			}
+		}
+	}
+
+	return m
+}
+
+// readManifests reads the manifest file of the checksum file with the given
+// name, if any. A manifest file that does not exist or cannot be decoded is
+// treated as an empty one.
+func readManifests(repo fs.FS, name string) (manifests, bool) {
+	raw, err := fs.ReadFile(repo, manifestPath(name))
+	if err != nil {
+		return manifests{}, false
+	}
+
+	m, err := decodeManifests(raw)
+	if err != nil {
+		return manifests{}, true
+	}
+
+	return m, true
+}
+
+func writeManifests(base, name string, m manifests) error {
+	fullManifestPath := path.Join(base, manifestPath(name))
+	if err := os.WriteFile(fullManifestPath, []byte(encodeManifests(m)), 0o644); err != nil {
+		return fmt.Errorf("could not write the manifest file: %v", err)
+	}
+
+	return nil
+}
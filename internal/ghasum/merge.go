@@ -0,0 +1,245 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+const (
+	// attributesFile is the path of the git attributes file, relative to the
+	// repository, in which the merge driver is assigned to checksum files.
+	attributesFile = ".gitattributes"
+
+	// mergeDriver is the name of the git merge driver for checksum files.
+	mergeDriver = "ghasum"
+)
+
+// addAttributes assigns the merge driver to the given patterns in the git
+// attributes file of the repository at base, unless already assigned.
+func addAttributes(base string, patterns []string) error {
+	fullPath := path.Join(base, attributesFile)
+
+	raw, err := os.ReadFile(fullPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not read %s: %v", attributesFile, err)
+	}
+
+	content := string(raw)
+	existing := strings.Split(content, "\n")
+	for i, line := range existing {
+		existing[i] = strings.Join(strings.Fields(line), " ")
+	}
+
+	changed := false
+	for _, pattern := range patterns {
+		line := fmt.Sprintf("%s merge=%s", pattern, mergeDriver)
+		if slices.Contains(existing, line) {
+			continue
+		}
+
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+
+		content += line + "\n"
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("could not write %s: %v", attributesFile, err)
+	}
+
+	return nil
+}
+
+// attributePatterns returns the git attributes patterns that match the checksum
+// files for the given configuration.
+func attributePatterns(cfg *Config) ([]string, error) {
+	root, err := locate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0, 4)
+	if cfg.Sumfile == "" && root != ghasumPath {
+		patterns = append(patterns, ghasumPath)
+	}
+
+	patterns = append(patterns, root)
+	if isSplit(cfg.Repo, root) {
+		dir := path.Dir(root)
+		patterns = append(patterns, path.Join(dir, "*.yml.sum"), path.Join(dir, "*.yaml.sum"))
+	}
+
+	return patterns, nil
+}
+
+// entryKey returns a key that uniquely identifies the given entry.
+func entryKey(entry sumfile.Entry) string {
+	return strings.Join(entry.ID, "\x00")
+}
+
+// merge3 merges the change from base to ours with the change from base to
+// theirs. It reports whether this is possible, which is not the case if both
+// sides changed the value differently.
+func merge3[T any](base, ours, theirs T, equal func(a, b T) bool) (T, bool) {
+	switch {
+	case equal(ours, theirs), equal(theirs, base):
+		return ours, true
+	case equal(ours, base):
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// mergeDocuments merges the changes from base to ours and from base to theirs
+// into a new document, with problems for all conflicting changes.
+func mergeDocuments(base, ours, theirs sumfile.Document) (sumfile.Document, []Problem) {
+	problems := make([]Problem, 0)
+
+	equal := func(a, b sumfile.Version) bool { return a == b }
+	version, ok := merge3(base.Version, ours.Version, theirs.Version, equal)
+	if !ok {
+		problems = append(problems, Problem{Kind: ProblemConflict})
+	}
+
+	headers, conflicts := mergeHeaders(base.Headers, ours.Headers, theirs.Headers)
+	problems = append(problems, conflicts...)
+
+	entries, conflicts := mergeEntries(base.Entries, ours.Entries, theirs.Entries)
+	problems = append(problems, conflicts...)
+
+	doc := sumfile.Document{
+		Version: version,
+		Headers: headers,
+		Entries: entries,
+	}
+
+	return doc, problems
+}
+
+// mergeEntries merges the changes to individual entries from base to ours and
+// from base to theirs, with problems for all conflicting changes.
+func mergeEntries(base, ours, theirs []sumfile.Entry) ([]sumfile.Entry, []Problem) {
+	index := func(entries []sumfile.Entry) map[string]*sumfile.Entry {
+		m := make(map[string]*sumfile.Entry, len(entries))
+		for i := range entries {
+			m[entryKey(entries[i])] = &entries[i]
+		}
+
+		return m
+	}
+
+	baseIndex, oursIndex, theirsIndex := index(base), index(ours), index(theirs)
+
+	keys := slices.Collect(maps.Keys(oursIndex))
+	keys = append(keys, slices.Collect(maps.Keys(theirsIndex))...)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	entries := make([]sumfile.Entry, 0, len(keys))
+	problems := make([]Problem, 0)
+	for _, key := range keys {
+		o, t := oursIndex[key], theirsIndex[key]
+
+		entry, ok := merge3(baseIndex[key], o, t, sameEntry)
+		if !ok {
+			id := strings.ReplaceAll(key, "\x00", "@")
+			p := Problem{Kind: ProblemConflict, ID: id}
+			if o != nil && t != nil && o.Checksum != t.Checksum {
+				p.Expected, p.Actual = o.Checksum, t.Checksum
+			}
+
+			problems = append(problems, p)
+		}
+
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries, problems
+}
+
+// mergeHeaders merges the changes to individual headers from base to ours and
+// from base to theirs, with problems for all conflicting changes. The generator
+// header never conflicts, the value from ours is used instead, and signature
+// headers are dropped because they do not cover the merged content.
+func mergeHeaders(base, ours, theirs map[string]string) (map[string]string, []Problem) {
+	lookup := func(headers map[string]string, name string) *string {
+		if value, ok := headers[name]; ok {
+			return &value
+		}
+
+		return nil
+	}
+
+	equal := func(a, b *string) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+
+	names := slices.Collect(maps.Keys(ours))
+	names = append(names, slices.Collect(maps.Keys(theirs))...)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	headers := make(map[string]string, len(names))
+	problems := make([]Problem, 0)
+	for _, name := range names {
+		if name == signatureHeader {
+			continue
+		}
+
+		b, o, t := lookup(base, name), lookup(ours, name), lookup(theirs, name)
+
+		value, ok := merge3(b, o, t, equal)
+		if !ok && name != generatorHeader {
+			problems = append(problems, Problem{Kind: ProblemConflict, Header: name})
+		}
+
+		if value != nil {
+			headers[name] = *value
+		}
+	}
+
+	return headers, problems
+}
+
+// sameEntry reports whether the given entries are the same, where nil means the
+// entry does not exist.
+func sameEntry(a, b *sumfile.Entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Checksum == b.Checksum &&
+		maps.Equal(a.Attributes, b.Attributes) &&
+		slices.Equal(a.Comments, b.Comments)
+}
@@ -16,12 +16,23 @@ package ghasum
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"maps"
+	"os"
+	"path"
 	"slices"
 
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+
 	"github.com/ericcornelissen/ghasum/internal/cache"
 	"github.com/ericcornelissen/ghasum/internal/checksum"
+	"github.com/ericcornelissen/ghasum/internal/gha"
+	"github.com/ericcornelissen/ghasum/internal/github"
+	"github.com/ericcornelissen/ghasum/internal/sbom"
+	"github.com/ericcornelissen/ghasum/internal/sshsig"
 	"github.com/ericcornelissen/ghasum/internal/sumfile"
 )
 
@@ -39,6 +50,11 @@ type (
 		// non-read file system operation.
 		Path string
 
+		// Sumfile is the file path (relative to Path) of the checksum file. If this
+		// has the zero value the checksum file at the default path is used, or the
+		// checksum file that it points to using the sumfile header.
+		Sumfile string
+
 		// Workflow is the file path (relative to Path) of the workflow that is the
 		// subject of the operation. If this has the zero value all workflows in the
 		// Repo will collectively be the subject of the operation instead.
@@ -56,152 +72,646 @@ type (
 		// Offline sets whether to rely exclusively on the cache or fetch missing
 		// repositories from the internet.
 		//
-		// Only applies to verification.
+		// Only applies to verification and migration.
 		Offline bool
-	}
 
-	// Problem represents an issue detected when verifying ghasum checksums.
-	Problem string
+		// Manifest sets whether to store the manifest of the files covered by
+		// every checksum in a manifest file next to the checksum file, which is
+		// used to explain checksum mismatches. An existing manifest file is always
+		// kept up-to-date.
+		//
+		// Only applies to initialization, updating, and migration.
+		Manifest bool
+
+		// Split sets whether to use a separate checksum file for every workflow,
+		// next to the checksum file. The layout is recorded in the checksum file
+		// so later operations use it automatically.
+		//
+		// Only applies to initialization.
+		Split bool
+
+		// Generator identifies the program writing the checksum file, which is
+		// recorded in the generator header of the checksum file. If this has the
+		// zero value the header is left as is.
+		//
+		// Only applies to initialization, merging, updating, and migration.
+		Generator string
+
+		// Algo is the hashing algorithm used to compute new checksums. Defaults to
+		// SHA256.
+		//
+		// Only applies to initialization and updating.
+		Algo checksum.Algo
+
+		// Limits bound the resources used to fetch and hash the repository of any
+		// single action. A limit with the zero value is not enforced.
+		Limits github.Limits
+
+		// Jobs is the maximum number of actions that are fetched and hashed at the
+		// same time. If this has the zero value the number of CPUs is used.
+		//
+		// Only applies to initialization, updating, migration, and verification.
+		Jobs int
+
+		// Signers are the signers trusted to sign checksum files. If this is not
+		// nil every checksum file must have a valid signature made by one of them,
+		// otherwise a problem is reported.
+		//
+		// Only applies to verification.
+		Signers []sshsig.AllowedSigner
+
+		// Strict enables additional checks on the checksum files, which report
+		// redundant checksums, checksum files that are not formatted, and
+		// checksum files that do not use the latest sumfile version as problems.
+		//
+		// Only applies to verification.
+		Strict bool
+	}
 )
 
-// Initialize will initialize ghasum for the repository specified in the given
-// configuration.
-func Initialize(cfg *Config) error {
-	file, err := create(cfg.Path)
+// Components returns the GitHub Actions in the checksums of the repository
+// specified in the given configuration as SBOM components, including their
+// checksums and the jobs in which they are used. An action that appears in
+// multiple checksum files is included once.
+func Components(cfg *Config) ([]sbom.Component, error) {
+	stored, err := allEntries(cfg)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	locations, err := usages(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	defer func() {
-		deinitialize := (err != nil)
-		if err = file.Close(); err != nil || deinitialize {
-			_ = remove(cfg.Path)
+	seen := make(map[string]bool, len(stored))
+	components := make([]sbom.Component, 0, len(stored))
+	for _, entry := range stored {
+		key := entryKey(entry)
+		if seen[key] {
+			continue
 		}
-	}()
 
-	actions, err := find(cfg)
+		seen[key] = true
+
+		c, err := component(entry, locations)
+		if err != nil {
+			return nil, err
+		}
+
+		components = append(components, c)
+	}
+
+	return components, nil
+}
+
+// Diff will compute the changes that updating the ghasum checksums for the
+// repository specified in the given configuration would make, without changing
+// anything and without locking the checksum files.
+//
+// If any action cannot be hashed safely the problems are returned instead.
+func Diff(cfg *Config, force bool) ([]Change, []Problem, error) {
+	root, err := locate(cfg)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	checksums, err := compute(cfg, actions, checksum.BestAlgo)
+	targets, err := units(cfg, root)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	changes := make([]Change, 0)
+	problems := make([]Problem, 0)
+	for _, u := range targets {
+		raw, err := read(cfg.Repo, u.name)
+		if u.optional && errors.Is(err, ErrNotInitialized) {
+			raw = nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		change := pending{unit: u}
+		checksums, _, changeProblems, err := update(cfg, &change, raw, force)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var stored []sumfile.Entry
+		if raw != nil {
+			doc, _ := decodeDocument("", raw)
+			stored = doc.Entries
+		}
+
+		problems = append(problems, within(u.name, changeProblems)...)
+		changes = append(changes, diff(u.name, stored, checksums)...)
+	}
+
+	if len(problems) > 0 {
+		problems, err := report(cfg, targets, problems)
+		return nil, problems, err
 	}
 
-	content, err := encode(sumfile.VersionLatest, checksums)
+	if isSplit(cfg.Repo, root) && cfg.Workflow == "" {
+		names, err := orphans(cfg, root, targets)
+		if err != nil {
+			return nil, nil, errors.Join(ErrSumfileRead, err)
+		}
+
+		for _, name := range names {
+			raw, err := read(cfg.Repo, name)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			doc, _ := decodeDocument("", raw)
+			changes = append(changes, diff(name, doc.Entries, nil)...)
+		}
+	}
+
+	return changes, nil, nil
+}
+
+// ExportCache will write an archive of the cache entries needed for the
+// checksums of the repository specified in the given configuration to w.
+func ExportCache(cfg *Config, w io.Writer) error {
+	stored, err := allEntries(cfg)
 	if err != nil {
 		return err
 	}
 
-	if err := write(file, content); err != nil {
-		return err
+	entries := make([]string, 0, len(stored))
+	for _, entry := range stored {
+		key, err := cacheKey(entry)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(entries, key) {
+			entries = append(entries, key)
+		}
 	}
 
-	if err := unlock(cfg.Path); err != nil {
-		return err
+	if err := cache.Export(cfg.Cache, w, entries); err != nil {
+		return fmt.Errorf("could not export cache: %v", err)
 	}
 
 	return nil
 }
 
-// Update will update the ghasum checksums for the repository specified in the
-// given configuration.
-func Update(cfg *Config, force bool) error {
-	file, err := open(cfg.Path)
+// Format rewrites the checksum files of the repository specified in the given
+// configuration in their canonical form, as written by ghasum, without changing
+// any checksum. It returns the names of the checksum files that were not in the
+// canonical form. If check is set these are only reported and not rewritten.
+//
+// An inline signature is removed from a checksum file that is rewritten.
+func Format(cfg *Config, check bool) ([]string, error) {
+	root, err := locate(cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer func() {
-		_ = unlock(cfg.Path)
+	targets, err := units(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, name := range sumfiles(cfg, root, targets) {
+		raw, err := read(cfg.Repo, name)
+		if err != nil {
+			return nil, err
+		}
+
+		content, differs, err := canonical(path.Join(cfg.Path, name), raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if !differs {
+			continue
+		}
+
+		changed = append(changed, name)
+		if check {
+			continue
+		}
+
+		file, err := os.OpenFile(path.Join(cfg.Path, name), os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, errors.Join(ErrSumfileWrite, err)
+		}
+
+		err = write(file, content)
 		_ = file.Close()
-	}()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
 
-	raw, err := io.ReadAll(file)
+// ImportCache will add the cache entries in the archive read from r to the
+// cache. Every entry in the archive must match a checksum of the repository
+// specified in the given configuration, otherwise nothing is imported.
+func ImportCache(cfg *Config, r io.Reader) error {
+	stored, err := allEntries(cfg)
 	if err != nil {
-		return errors.Join(ErrSumfileRead, err)
+		return err
 	}
 
-	version, err := version(raw)
-	oldChecksums, _ := decode(raw)
-	if err != nil {
-		if !force {
-			return errors.Join(ErrSumfileRead, err)
+	checksums := make(map[string]string, len(stored))
+	for _, entry := range stored {
+		key, err := cacheKey(entry)
+		if err != nil {
+			return err
 		}
 
-		if errors.Is(err, sumfile.ErrHeaders) || errors.Is(err, sumfile.ErrVersion) {
-			version = sumfile.VersionLatest
+		checksums[key] = entry.Checksum
+	}
+
+	verify := func(entry string, files fs.FS) error {
+		want, ok := checksums[entry]
+		if !ok {
+			return errors.New("no checksum found")
+		}
+
+		algo, err := checksum.Parse(want)
+		if err != nil {
+			return err
+		}
+
+		got, _, err := checksum.ComputeManifest(files, algo, hashLimits(cfg))
+		if err != nil {
+			return err
+		}
+
+		if got != want {
+			return errors.New("checksum mismatch")
 		}
+
+		return nil
 	}
 
-	actions, err := find(cfg)
+	if err := cache.Import(cfg.Cache, r, cfg.Limits, verify); err != nil {
+		return fmt.Errorf("could not import cache: %v", err)
+	}
+
+	return nil
+}
+
+// Initialize will initialize ghasum for the repository specified in the given
+// configuration.
+func Initialize(cfg *Config) error {
+	root, err := locate(cfg)
 	if err != nil {
 		return err
 	}
 
-	checksums, err := compute(cfg, actions, checksum.BestAlgo)
+	if !cfg.Split {
+		actions, err := find(cfg, cfg.Workflow)
+		if err != nil {
+			return err
+		}
+
+		files, err := initialize(cfg, root, nil, actions)
+		if err != nil {
+			return err
+		}
+
+		return initializeManifests(cfg, root, files)
+	}
+
+	workflows, err := gha.Workflows(cfg.Repo)
 	if err != nil {
+		return fmt.Errorf("could not get workflows: %v", err)
+	}
+
+	actions := make([][]gha.GitHubAction, len(workflows))
+	for i, workflow := range workflows {
+		if actions[i], err = find(cfg, workflow); err != nil {
+			return err
+		}
+	}
+
+	headers := map[string]string{layoutHeader: layoutSplit}
+	if _, err := initialize(cfg, root, headers, nil); err != nil {
 		return err
 	}
 
-	if !force {
-		for i, entry := range checksums {
-			for _, oldEntry := range oldChecksums {
-				if slices.Equal(entry.ID, oldEntry.ID) {
-					checksums[i] = oldEntry
-					break
-				}
+	files := make(manifests)
+	for i, workflow := range workflows {
+		workflowFiles, err := initialize(cfg, workflowSumfile(root, workflow), nil, actions[i])
+		if err != nil {
+			for _, workflow := range workflows[:i] {
+				_ = remove(cfg.Path, workflowSumfile(root, workflow))
 			}
+
+			_ = remove(cfg.Path, root)
+			return err
 		}
+
+		maps.Copy(files, workflowFiles)
 	}
 
-	encoded, err := encode(version, checksums)
+	return initializeManifests(cfg, root, files)
+}
+
+// InstallMergeDriver will configure the repository specified in the given
+// configuration to merge its checksum files using the given command. The merge
+// driver is registered in the git configuration of the repository and assigned
+// to the checksum files in its .gitattributes file.
+func InstallMergeDriver(cfg *Config, command string) error {
+	repo, err := git.PlainOpen(cfg.Path)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not open the git repository: %v", err)
+	}
+
+	gitCfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("could not read the git configuration: %v", err)
+	}
+
+	section := gitCfg.Raw.Section("merge").Subsection(mergeDriver)
+	section.SetOption("name", "ghasum checksum file merge driver")
+	section.SetOption("driver", command)
+	if err := repo.SetConfig(gitCfg); err != nil {
+		return fmt.Errorf("could not write the git configuration: %v", err)
 	}
 
-	if err := clear(file); err != nil {
+	patterns, err := attributePatterns(cfg)
+	if err != nil {
 		return err
 	}
 
-	if err := write(file, encoded); err != nil {
+	return addAttributes(cfg.Path, patterns)
+}
+
+// Merge will merge the changes made to a checksum file in ours and in theirs,
+// given the content of their common ancestor in base, at the level of headers
+// and entries. An empty base is treated as a checksum file without headers or
+// entries. If any header or entry is changed differently on both sides, for
+// example if an entry has a different checksum, the conflicts are returned.
+func Merge(cfg *Config, base, ours, theirs []byte) (string, []Problem, error) {
+	var baseDoc sumfile.Document
+	if len(base) > 0 {
+		var err error
+		if baseDoc, err = decodeDocument("", base); err != nil {
+			return "", nil, err
+		}
+	}
+
+	oursDoc, err := decodeDocument("", ours)
+	if err != nil {
+		return "", nil, err
+	}
+
+	theirsDoc, err := decodeDocument("", theirs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	doc, problems := mergeDocuments(baseDoc, oursDoc, theirsDoc)
+	if len(problems) > 0 {
+		sortProblems(problems)
+		return "", problems, nil
+	}
+
+	content, err := encode(doc, cfg.Generator)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return content, nil, nil
+}
+
+// Migrate will rewrite the ghasum checksums for the repository specified in the
+// given configuration using the given sumfile version and hashing algorithm.
+//
+// Every stored checksum is first verified using the algorithm it was computed
+// with. If any checksum does not match the problems are returned and nothing is
+// changed, so that a mismatch is never replaced by a new checksum.
+func Migrate(cfg *Config, version sumfile.Version, algo checksum.Algo) ([]Problem, error) {
+	if version < sumfile.Version1 || version > sumfile.VersionLatest {
+		return nil, fmt.Errorf("unknown sumfile version %d", version)
+	}
+
+	root, err := locate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := units(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if isSplit(cfg.Repo, root) {
+		targets = append([]unit{{name: root}}, targets...)
+	}
+
+	changes := make([]pending, 0, len(targets))
+	defer func() { release(cfg, changes) }()
+
+	for _, u := range targets {
+		file, err := open(cfg.Path, u.name)
+		if u.optional && errors.Is(err, ErrNotInitialized) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, pending{unit: u, file: file})
+	}
+
+	storedFiles, hasManifests := readManifests(cfg.Repo, root)
+
+	problems := make([]Problem, 0)
+	files := make(manifests)
+	for i := range changes {
+		changeFiles, changeProblems, err := migrate(cfg, &changes[i], version, algo, storedFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, within(changes[i].name, changeProblems)...)
+		maps.Copy(files, changeFiles)
+	}
+
+	if len(problems) > 0 {
+		return report(cfg, targets, problems)
+	}
+
+	if err := commit(cfg, changes); err != nil {
+		return nil, err
+	}
+
+	if cfg.Manifest || hasManifests {
+		if err := writeManifests(cfg.Path, root, files); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Sign will sign the checksum files for the repository specified in the given
+// configuration using the given signer. The signature is stored in the checksum
+// file itself, or in a file next to it if detached is set.
+//
+// In the split layout the checksum file of every workflow is signed as well.
+func Sign(cfg *Config, signer ssh.Signer, detached bool) error {
+	root, err := locate(cfg)
+	if err != nil {
 		return err
 	}
 
-	if err := unlock(cfg.Path); err != nil {
+	targets, err := units(cfg, root)
+	if err != nil {
 		return err
 	}
 
+	for _, name := range sumfiles(cfg, root, targets) {
+		if err := sign(cfg, name, signer, detached); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// Verify will compare the stored ghasum checksums against recomputed checksums
-// for the repository specified in the given configuration.
+// Update will update the ghasum checksums for the repository specified in the
+// given configuration.
 //
-// Verification report checksums that do not match and checksums that are
-// missing. It does not report checksums that are not used.
-func Verify(cfg *Config) ([]Problem, error) {
-	raw, err := read(cfg.Repo)
+// If any action cannot be hashed safely the problems are returned and nothing
+// is changed.
+func Update(cfg *Config, force bool) ([]Problem, error) {
+	root, err := locate(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	stored, err := decode(raw)
+	targets, err := units(cfg, root)
 	if err != nil {
 		return nil, err
 	}
 
-	actions, err := find(cfg)
+	changes := make([]pending, 0, len(targets))
+	defer func() { release(cfg, changes) }()
+
+	for _, u := range targets {
+		file, err := open(cfg.Path, u.name)
+		if u.optional && errors.Is(err, ErrNotInitialized) {
+			file = nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, pending{unit: u, file: file})
+	}
+
+	problems := make([]Problem, 0)
+	checksums := make([]sumfile.Entry, 0)
+	files := make(manifests)
+	for i := range changes {
+		var raw []byte
+		if changes[i].file != nil {
+			if raw, err = io.ReadAll(changes[i].file); err != nil {
+				return nil, errors.Join(ErrSumfileRead, err)
+			}
+		}
+
+		changeChecksums, changeFiles, changeProblems, err := update(cfg, &changes[i], raw, force)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, within(changes[i].name, changeProblems)...)
+		checksums = append(checksums, changeChecksums...)
+		maps.Copy(files, changeFiles)
+	}
+
+	if len(problems) > 0 {
+		return report(cfg, targets, problems)
+	}
+
+	if err := commit(cfg, changes); err != nil {
+		return nil, err
+	}
+
+	if isSplit(cfg.Repo, root) && cfg.Workflow == "" {
+		if err := prune(cfg, root, targets); err != nil {
+			return nil, err
+		}
+	}
+
+	if oldFiles, hasManifests := readManifests(cfg.Repo, root); cfg.Manifest || hasManifests {
+		if err := writeManifests(cfg.Path, root, matching(checksums, files, oldFiles)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Verify will compare the stored ghasum checksums against recomputed checksums
+// for the repository specified in the given configuration.
+//
+// Verification report checksums that do not match and checksums that are
+// missing. It does not report checksums that are not used, unless the strict
+// checks are enabled in the configuration.
+func Verify(cfg *Config) ([]Problem, error) {
+	root, err := locate(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	fresh, err := compute(cfg, actions, checksum.Sha256)
+	targets, err := units(cfg, root)
 	if err != nil {
 		return nil, err
 	}
 
-	result := compare(fresh, stored)
-	return result, nil
+	storedFiles, _ := readManifests(cfg.Repo, root)
+
+	result := make([]Problem, 0)
+	if cfg.Signers != nil {
+		problems, err := verifySignatures(cfg, root, targets)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, problems...)
+	}
+
+	if cfg.Strict {
+		problems, err := checkStrict(cfg, root, targets)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, problems...)
+	}
+
+	for _, u := range targets {
+		stored, err := readUnit(cfg, u)
+		if err != nil {
+			return nil, err
+		}
+
+		actions, err := find(cfg, u.workflow)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh, freshFiles, problems, err := compute(cfg, actions, stored, checksum.BestAlgo)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, within(u.name, problems)...)
+		result = append(result, within(u.name, compare(fresh, stored, freshFiles, storedFiles))...)
+	}
+
+	return report(cfg, targets, result)
 }
@@ -0,0 +1,103 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"runtime"
+	"sync"
+)
+
+// A keyedMutex is a set of mutual exclusion locks identified by a key. The zero
+// value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the lock identified by the given key and returns a function that
+// unlocks it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*sync.Mutex)
+	}
+
+	l, ok := m.locks[key]
+	if !ok {
+		l = new(sync.Mutex)
+		m.locks[key] = l
+	}
+	m.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// forEach calls fn for every index from 0 up to count, using at most jobs
+// goroutines at the same time (or one per CPU if jobs is not positive).
+//
+// If fn errors for an index it is not called anymore for higher indices, and
+// the error for the lowest index is returned. Since fn is always called for all
+// indices below that, the error does not depend on scheduling.
+func forEach(jobs, count int, fn func(i int) error) error {
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	var (
+		errs    = make([]error, count)
+		failed  = count
+		indices = make(chan int)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	for range min(jobs, count) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				mu.Lock()
+				skip := i > failed
+				mu.Unlock()
+
+				if skip {
+					continue
+				}
+
+				if err := fn(i); err != nil {
+					mu.Lock()
+					errs[i], failed = err, min(failed, i)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range count {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
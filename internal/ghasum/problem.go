@@ -0,0 +1,212 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/gha"
+)
+
+// ProblemKind is the kind of a Problem.
+type ProblemKind string
+
+const (
+	// ProblemMissing is the kind of problem where an action has no checksum.
+	ProblemMissing ProblemKind = "missing"
+
+	// ProblemMismatch is the kind of problem where the checksum of an action
+	// does not match its stored checksum.
+	ProblemMismatch ProblemKind = "mismatch"
+
+	// ProblemUnsafe is the kind of problem where an action cannot be hashed
+	// safely, for example because it contains a symbolic link escaping the
+	// repository.
+	ProblemUnsafe ProblemKind = "unsafe"
+
+	// ProblemFetchFailed is the kind of problem where the repository of an
+	// action could not be fetched.
+	ProblemFetchFailed ProblemKind = "fetch-failed"
+
+	// ProblemUnsigned is the kind of problem where a checksum file has no
+	// signature.
+	ProblemUnsigned ProblemKind = "unsigned"
+
+	// ProblemSignature is the kind of problem where the signature of a checksum
+	// file is invalid.
+	ProblemSignature ProblemKind = "invalid-signature"
+
+	// ProblemRedundant is the kind of problem where a checksum file contains a
+	// checksum for an action that is not used.
+	ProblemRedundant ProblemKind = "redundant"
+
+	// ProblemUnformatted is the kind of problem where a checksum file is not in
+	// its canonical format.
+	ProblemUnformatted ProblemKind = "unformatted"
+
+	// ProblemOutdated is the kind of problem where a checksum file does not use
+	// the latest sumfile version.
+	ProblemOutdated ProblemKind = "outdated"
+
+	// ProblemConflict is the kind of problem where the version, a header, or an
+	// entry of a checksum file is changed differently on both sides of a merge.
+	ProblemConflict ProblemKind = "conflict"
+)
+
+// Problem represents an issue detected when verifying ghasum checksums.
+type Problem struct {
+	// Kind is the kind of problem.
+	Kind ProblemKind
+
+	// ID is the identifier of the action the problem is about, for example
+	// "actions/checkout@v4". It is empty if the problem is not about an action.
+	ID string
+
+	// Header is the name of the header the problem is about. It is only set for
+	// conflicting headers.
+	Header string
+
+	// File is the path of the checksum file the problem is about, relative to
+	// the repository. It is empty if the problem is not about a file on disk.
+	File string
+
+	// Expected is the stored checksum and Actual is the computed checksum of
+	// the action. For conflicts they are the checksums in ours and in theirs,
+	// and for outdated checksum files the latest and the used sumfile version.
+	Expected, Actual string
+
+	// Added, Removed, and Modified are the files of the action that changed
+	// since the stored checksum was computed, if this is known.
+	Added, Removed, Modified []string
+
+	// Locations are the places where the action is used, as "<workflow>:<job>".
+	Locations []string
+
+	// Detail is a description of the cause of the problem, if any.
+	Detail string
+}
+
+// String returns a human readable description of the problem.
+func (p Problem) String() string {
+	var sb strings.Builder
+	switch p.Kind {
+	case ProblemMissing:
+		sb.WriteString(fmt.Sprintf("no checksum found for %q", p.ID))
+	case ProblemMismatch:
+		sb.WriteString(fmt.Sprintf("checksum mismatch for %q", p.ID))
+		for _, name := range p.Added {
+			sb.WriteString(fmt.Sprintf("\n    added: %s", name))
+		}
+
+		for _, name := range p.Removed {
+			sb.WriteString(fmt.Sprintf("\n    removed: %s", name))
+		}
+
+		for _, name := range p.Modified {
+			sb.WriteString(fmt.Sprintf("\n    modified: %s", name))
+		}
+	case ProblemUnsafe:
+		sb.WriteString(fmt.Sprintf("cannot safely hash %q: %s", p.ID, p.Detail))
+	case ProblemFetchFailed:
+		sb.WriteString(fmt.Sprintf("cannot fetch %q: %s", p.ID, p.Detail))
+	case ProblemUnsigned:
+		sb.WriteString(fmt.Sprintf("no signature found for %q", p.File))
+	case ProblemSignature:
+		sb.WriteString(fmt.Sprintf("invalid signature for %q: %s", p.File, p.Detail))
+	case ProblemRedundant:
+		sb.WriteString(fmt.Sprintf("redundant checksum for %q", p.ID))
+	case ProblemUnformatted:
+		sb.WriteString(fmt.Sprintf("checksum file %q is not formatted", p.File))
+	case ProblemOutdated:
+		sb.WriteString(fmt.Sprintf("checksum file %q uses sumfile version %s, latest is %s", p.File, p.Actual, p.Expected))
+	case ProblemConflict:
+		switch {
+		case p.Header != "":
+			sb.WriteString(fmt.Sprintf("conflicting values for header %q", p.Header))
+		case p.ID == "":
+			sb.WriteString("conflicting sumfile versions")
+		case p.Expected != "" && p.Actual != "":
+			sb.WriteString(fmt.Sprintf("conflicting checksums for %q", p.ID))
+		default:
+			sb.WriteString(fmt.Sprintf("conflicting changes for %q", p.ID))
+		}
+	default:
+		sb.WriteString(fmt.Sprintf("%s problem for %q", p.Kind, p.ID))
+	}
+
+	return sb.String()
+}
+
+// report completes the given problems with the locations of the actions they
+// are about, as far as they are used in the workflows of the given units, and
+// sorts them.
+func report(cfg *Config, targets []unit, problems []Problem) ([]Problem, error) {
+	if !slices.ContainsFunc(problems, func(p Problem) bool { return p.ID != "" }) {
+		sortProblems(problems)
+		return problems, nil
+	}
+
+	workflows := make([]string, 0, len(targets))
+	for _, u := range targets {
+		if u.workflow == "" {
+			var err error
+			if workflows, err = gha.Workflows(cfg.Repo); err != nil {
+				return nil, fmt.Errorf("could not get workflows: %v", err)
+			}
+
+			break
+		}
+
+		workflows = append(workflows, u.workflow)
+	}
+
+	locations := make(map[string][]string)
+	for _, workflow := range workflows {
+		if err := workflowUsages(cfg, workflow, locations); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range problems {
+		problems[i].Locations = locations[problems[i].ID]
+	}
+
+	sortProblems(problems)
+	return problems, nil
+}
+
+// sortProblems sorts the given problems by file, action, header, and kind.
+func sortProblems(problems []Problem) {
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.ID, b.ID),
+			cmp.Compare(a.Header, b.Header),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+}
+
+// within sets the checksum file of the given problems to the given name.
+func within(name string, problems []Problem) []Problem {
+	for i := range problems {
+		problems[i].File = name
+	}
+
+	return problems
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/checksum"
+	"github.com/ericcornelissen/ghasum/internal/gha"
+	"github.com/ericcornelissen/ghasum/internal/sbom"
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+// component converts the given entry into an SBOM component used in the given
+// locations.
+func component(entry sumfile.Entry, locations map[string][]string) (sbom.Component, error) {
+	id := strings.Join(entry.ID, "@")
+	if len(entry.ID) != 2 {
+		return sbom.Component{}, fmt.Errorf("invalid id %q", id)
+	}
+
+	owner, project, ok := strings.Cut(entry.ID[0], "/")
+	if !ok {
+		return sbom.Component{}, fmt.Errorf("invalid id %q", id)
+	}
+
+	if _, err := checksum.Parse(entry.Checksum); err != nil {
+		return sbom.Component{}, fmt.Errorf("invalid checksum for %q: %v", id, err)
+	}
+
+	return sbom.Component{
+		Owner:     owner,
+		Project:   project,
+		Ref:       entry.ID[1],
+		Checksum:  entry.Checksum,
+		Locations: locations[id],
+	}, nil
+}
+
+// usages returns the jobs, in the form "<workflow>:<job>", that use each action
+// in the repository, keyed by the action in the form "<owner>/<project>@<ref>".
+func usages(cfg *Config) (map[string][]string, error) {
+	workflows, err := gha.Workflows(cfg.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("could not get workflows: %v", err)
+	}
+
+	locations := make(map[string][]string)
+	for _, workflow := range workflows {
+		if err := workflowUsages(cfg, workflow, locations); err != nil {
+			return nil, err
+		}
+	}
+
+	return locations, nil
+}
+
+// workflowUsages adds the jobs, in the form "<workflow>:<job>", of the given
+// workflow that use each action to the given locations, keyed by the action in
+// the form "<owner>/<project>@<ref>".
+func workflowUsages(cfg *Config, workflow string, locations map[string][]string) error {
+	jobs, err := gha.Jobs(cfg.Repo, workflow)
+	if err != nil {
+		return fmt.Errorf("could not get jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		actions, err := gha.JobActions(cfg.Repo, workflow, job)
+		if err != nil {
+			return fmt.Errorf("could not get GitHub Actions: %v", err)
+		}
+
+		location := fmt.Sprintf("%s:%s", workflow, job)
+		for _, action := range actions {
+			id := fmt.Sprintf("%s/%s@%s", action.Owner, action.Project, action.Ref)
+			if n := len(locations[id]); n == 0 || locations[id][n-1] != location {
+				locations[id] = append(locations[id], location)
+			}
+		}
+	}
+
+	return nil
+}
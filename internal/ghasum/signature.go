@@ -0,0 +1,159 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ericcornelissen/ghasum/internal/sshsig"
+)
+
+const (
+	// signatureHeader is the name of the sumfile header holding the inline
+	// signature over the rest of the checksum file.
+	signatureHeader = "signature"
+
+	// signatureSuffix is the suffix of the file holding the detached signature
+	// over a checksum file, next to the checksum file.
+	signatureSuffix = ".sig"
+)
+
+// checkSignature verifies the signature of the checksum file with the given
+// name and raw content against the signers in the given configuration. An
+// inline signature takes precedence over a detached signature.
+func checkSignature(cfg *Config, name string, raw []byte) []Problem {
+	message, encoded, inline := splitSignature(raw)
+
+	var (
+		signature []byte
+		err       error
+	)
+
+	if inline {
+		signature, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			err = sshsig.ErrSignature
+		}
+	} else {
+		var armored []byte
+		armored, err = read(cfg.Repo, name+signatureSuffix)
+		if errors.Is(err, ErrNotInitialized) {
+			return []Problem{{Kind: ProblemUnsigned, File: name}}
+		} else if err == nil {
+			signature, err = sshsig.Unarmor(armored)
+		}
+	}
+
+	if err == nil {
+		_, err = sshsig.Verify(signature, message, cfg.Signers)
+	}
+
+	if err != nil {
+		return []Problem{{Kind: ProblemSignature, File: name, Detail: err.Error()}}
+	}
+
+	return nil
+}
+
+// sign signs the checksum file with the given name using the given signer. The
+// signature is stored in the signature header of the checksum file, or in a
+// separate file next to it if detached is set. Any existing inline signature is
+// replaced.
+func sign(cfg *Config, name string, signer ssh.Signer, detached bool) error {
+	raw, err := read(cfg.Repo, name)
+	if err != nil {
+		return err
+	}
+
+	message, _, _ := splitSignature(raw)
+	signature, err := sshsig.Sign(signer, message)
+	if err != nil {
+		return err
+	}
+
+	target, content := name, withSignature(message, signature)
+	if detached {
+		target, content = name+signatureSuffix, string(sshsig.Armor(signature))
+	}
+
+	file, err := os.OpenFile(path.Join(cfg.Path, target), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Join(ErrSumfileWrite, err)
+	}
+
+	defer file.Close()
+	return write(file, content)
+}
+
+// splitSignature splits the raw content of a checksum file into the message
+// covered by its inline signature and the encoded signature. The message is the
+// content without the signature header. If there is no inline signature the
+// message is the full content.
+func splitSignature(raw []byte) ([]byte, string, bool) {
+	lines := strings.SplitAfter(string(raw), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+
+		value, ok := strings.CutPrefix(line, signatureHeader+" ")
+		if ok {
+			message := strings.Join(append(lines[:i:i], lines[i+1:]...), "")
+			return []byte(message), strings.TrimSuffix(value, "\n"), true
+		}
+	}
+
+	return raw, "", false
+}
+
+// verifySignatures verifies the signatures of the checksum files for the given
+// configuration, given the path of its checksum file and its units.
+func verifySignatures(cfg *Config, root string, targets []unit) ([]Problem, error) {
+	problems := make([]Problem, 0)
+	for _, name := range sumfiles(cfg, root, targets) {
+		raw, err := read(cfg.Repo, name)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, checkSignature(cfg, name, raw)...)
+	}
+
+	return problems, nil
+}
+
+// withSignature returns the content of a checksum file with the given signature
+// added as a header, among the other headers in alphabetical order.
+func withSignature(message, signature []byte) string {
+	header := fmt.Sprintf("%s %s\n", signatureHeader, base64.StdEncoding.EncodeToString(signature))
+
+	lines := strings.SplitAfter(string(message), "\n")
+	i := 1
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" || lines[i] > header {
+			break
+		}
+	}
+
+	lines = append(lines[:i:i], append([]string{header}, lines[i:]...)...)
+	return strings.Join(lines, "")
+}
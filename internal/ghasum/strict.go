@@ -0,0 +1,127 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghasum
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ericcornelissen/ghasum/internal/gha"
+	"github.com/ericcornelissen/ghasum/internal/sumfile"
+)
+
+// checkStrict checks the checksum files for the given configuration, given the
+// path of its checksum file and its units, for problems that are only reported
+// in strict mode. Checksum files that only point to other checksum files are
+// not checked.
+func checkStrict(cfg *Config, root string, targets []unit) ([]Problem, error) {
+	problems := make([]Problem, 0)
+	for _, name := range sumfiles(cfg, root, targets) {
+		if isPointer(cfg.Repo, name) {
+			continue
+		}
+
+		raw, err := read(cfg.Repo, name)
+		if err != nil {
+			return nil, err
+		}
+
+		fileProblems, err := checkFile(cfg, name, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, fileProblems...)
+	}
+
+	split := isSplit(cfg.Repo, root)
+	for _, u := range targets {
+		stored, err := readUnit(cfg, u)
+		if err != nil {
+			return nil, err
+		}
+
+		// Outside the split layout the checksum file covers all workflows, even
+		// if only one of them is verified.
+		workflow := u.workflow
+		if !split {
+			workflow = ""
+		}
+
+		actions, err := find(&Config{Repo: cfg.Repo}, workflow)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, redundant(u.name, stored, actions)...)
+	}
+
+	return problems, nil
+}
+
+// checkFile checks the checksum file with the given name and raw content for
+// using the latest sumfile version and for being formatted.
+func checkFile(cfg *Config, name string, raw []byte) ([]Problem, error) {
+	file := path.Join(cfg.Path, name)
+
+	message, _, _ := splitSignature(raw)
+	doc, err := decodeDocument(file, message)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]Problem, 0)
+	if doc.Version != sumfile.VersionLatest {
+		problems = append(problems, Problem{
+			Kind:     ProblemOutdated,
+			File:     name,
+			Expected: strconv.Itoa(int(sumfile.VersionLatest)),
+			Actual:   strconv.Itoa(int(doc.Version)),
+		})
+	}
+
+	if _, changed, err := canonical(file, raw); err != nil {
+		return nil, err
+	} else if changed {
+		problems = append(problems, Problem{Kind: ProblemUnformatted, File: name})
+	}
+
+	return problems, nil
+}
+
+// redundant returns a problem for every stored checksum in the checksum file
+// with the given name that is not for any of the given actions.
+func redundant(name string, stored []sumfile.Entry, actions []gha.GitHubAction) []Problem {
+	used := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		used[fmt.Sprintf("%s/%s@%s", action.Owner, action.Project, action.Ref)] = true
+	}
+
+	problems := make([]Problem, 0)
+	for _, entry := range stored {
+		if id := strings.Join(entry.ID, "@"); !used[id] {
+			problems = append(problems, Problem{
+				Kind:     ProblemRedundant,
+				ID:       id,
+				File:     name,
+				Expected: entry.Checksum,
+			})
+		}
+	}
+
+	return problems
+}
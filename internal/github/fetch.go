@@ -0,0 +1,255 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
+
+	"github.com/ericcornelissen/ghasum/internal/vfs"
+)
+
+// A Repository represents a GitHub repository.
+type Repository struct {
+	// Owner is the name of the user or organization that owns the project.
+	Owner string
+
+	// Project is the name of the project to clone.
+	Project string
+
+	// Ref is the reference to check out.
+	Ref string
+}
+
+// Fetch will fetch the given repository at the exact ref from GitHub into the
+// given git object store and return the files at that ref. The object store is
+// initialized if it is empty and may be shared between refs, so objects already
+// in it are not fetched again.
+//
+// The fetch fails if the repository exceeds any of the given limits.
+func Fetch(objects storage.Storer, repo *Repository, limits Limits) (fs.FS, error) {
+	objects = limit(objects, limits.Download)
+	repository, err := openStore(objects, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := fetch(repository, repo)
+	if exceeded(objects) {
+		return nil, fmt.Errorf("could not fetch %s/%s@%s: more than %d bytes downloaded", repo.Owner, repo.Project, repo.Ref, limits.Download)
+	} else if err != nil {
+		return nil, err
+	}
+
+	files, err := checkout(commit, limits)
+	if err != nil {
+		return nil, fmt.Errorf("could not check out %q for %s/%s: %v", repo.Ref, repo.Owner, repo.Project, err)
+	}
+
+	return files, nil
+}
+
+func openStore(objects storage.Storer, repo *Repository) (*git.Repository, error) {
+	repository, err := git.Open(objects, nil)
+	if err == nil {
+		return repository, nil
+	} else if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("could not open object store for %s/%s: %v", repo.Owner, repo.Project, err)
+	}
+
+	repository, err = git.Init(objects, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create object store for %s/%s: %v", repo.Owner, repo.Project, err)
+	}
+
+	remote := config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{toUrl(repo)},
+	}
+
+	if _, err := repository.CreateRemote(&remote); err != nil {
+		return nil, fmt.Errorf("could not configure object store for %s/%s: %v", repo.Owner, repo.Project, err)
+	}
+
+	return repository, nil
+}
+
+func fetch(repository *git.Repository, repo *Repository) (*object.Commit, error) {
+	if commit, err := fetchTag(repository, repo); err == nil {
+		return commit, nil
+	}
+
+	if commit, err := fetchBranch(repository, repo); err == nil {
+		return commit, nil
+	}
+
+	return fetchCommit(repository, repo)
+}
+
+func fetchBranch(repository *git.Repository, repo *Repository) (*object.Commit, error) {
+	var (
+		src = plumbing.NewBranchReferenceName(repo.Ref)
+		dst = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, repo.Ref)
+	)
+
+	opts := git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{refSpec(src.String(), dst)},
+		Depth:      1,
+		Tags:       git.NoTags,
+	}
+
+	if err := repository.Fetch(&opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("could not fetch %q (as branch) from %q: %v", repo.Ref, toUrl(repo), err)
+	}
+
+	return resolve(repository, dst)
+}
+
+func fetchCommit(repository *git.Repository, repo *Repository) (*object.Commit, error) {
+	hash := plumbing.NewHash(repo.Ref)
+	if commit, err := repository.CommitObject(hash); err == nil {
+		return commit, nil
+	}
+
+	var (
+		dst  = plumbing.ReferenceName("refs/ghasum/" + repo.Ref)
+		opts = git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs:   []config.RefSpec{refSpec(repo.Ref, dst)},
+			Depth:      1,
+			Tags:       git.NoTags,
+		}
+	)
+
+	if err := repository.Fetch(&opts); err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return resolve(repository, dst)
+	}
+
+	opts = git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Tags:       git.NoTags,
+	}
+
+	if err := repository.Fetch(&opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("could not fetch from %q: %v", toUrl(repo), err)
+	}
+
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not find ref %q for %s/%s: %v", repo.Ref, repo.Owner, repo.Project, err)
+	}
+
+	return commit, nil
+}
+
+func fetchTag(repository *git.Repository, repo *Repository) (*object.Commit, error) {
+	name := plumbing.NewTagReferenceName(repo.Ref)
+	opts := git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{refSpec(name.String(), name)},
+		Depth:      1,
+		Tags:       git.NoTags,
+	}
+
+	if err := repository.Fetch(&opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("could not fetch %q (as tag) from %q: %v", repo.Ref, toUrl(repo), err)
+	}
+
+	return resolve(repository, name)
+}
+
+func resolve(repository *git.Repository, name plumbing.ReferenceName) (*object.Commit, error) {
+	ref, err := repository.Reference(name, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %q: %v", name, err)
+	}
+
+	hash := ref.Hash()
+	if tag, err := repository.TagObject(hash); err == nil {
+		commit, err := tag.Commit()
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %q: %v", name, err)
+		}
+
+		return commit, nil
+	}
+
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %q: %v", name, err)
+	}
+
+	return commit, nil
+}
+
+func checkout(commit *object.Commit, limits Limits) (fs.FS, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		count int
+		size  int64
+	)
+
+	files := vfs.New()
+	add := func(file *object.File) error {
+		count, size = count+1, size+file.Size
+		if err := checkLimits(limits, count, size); err != nil {
+			return err
+		}
+
+		if file.Mode == filemode.Symlink {
+			target, err := file.Contents()
+			if err != nil {
+				return err
+			}
+
+			return files.Symlink(target, file.Name)
+		}
+
+		perm := fs.FileMode(0o644)
+		if file.Mode == filemode.Executable {
+			perm = 0o755
+		}
+
+		return files.WriteLazyFile(file.Name, file.Size, file.Reader, perm)
+	}
+
+	if err := tree.Files().ForEach(add); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func refSpec(src string, dst plumbing.ReferenceName) config.RefSpec {
+	return config.RefSpec(fmt.Sprintf("+%s:%s", src, dst))
+}
+
+func toUrl(repo *Repository) (url string) {
+	return fmt.Sprintf("https://github.com/%s/%s", repo.Owner, repo.Project)
+}
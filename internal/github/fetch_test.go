@@ -0,0 +1,298 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestToUrl(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			in   Repository
+			want string
+		}
+
+		testCases := []TestCase{
+			{
+				in: Repository{
+					Owner:   "foo",
+					Project: "bar",
+				},
+				want: "https://github.com/foo/bar",
+			},
+			{
+				in: Repository{
+					Owner:   "ericcornelissen",
+					Project: "ghasum",
+				},
+				want: "https://github.com/ericcornelissen/ghasum",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.want, func(t *testing.T) {
+				got := toUrl(&tc.in)
+				if want := tc.want; got != want {
+					t.Errorf("Incorrect result (got %q, wan %q)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Arbitrary", func(t *testing.T) {
+		t.Parallel()
+
+		isGitHubUrl := func(repo Repository) bool {
+			url := toUrl(&repo)
+			return strings.HasPrefix(url, "https://github.com/")
+		}
+
+		if err := quick.Check(isGitHubUrl, nil); err != nil {
+			t.Errorf("Missing GitHub URL for: %v", err)
+		}
+
+		containsOwner := func(repo Repository) bool {
+			url := toUrl(&repo)
+			return strings.Contains(url, repo.Owner)
+		}
+
+		if err := quick.Check(containsOwner, nil); err != nil {
+			t.Errorf("Missing repository owner for: %v", err)
+		}
+
+		containsProject := func(repo Repository) bool {
+			url := toUrl(&repo)
+			return strings.Contains(url, repo.Project)
+		}
+
+		if err := quick.Check(containsProject, nil); err != nil {
+			t.Errorf("Missing repository project for: %v", err)
+		}
+	})
+}
+
+func TestCheckout(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"action.yml":      "name: example",
+		"src/index.js":    "console.log('Hello world!');",
+		"src/lib/util.js": "module.exports = {};",
+	}
+
+	commit := commitFiles(t, files)
+
+	checkedOut, err := checkout(commit, Limits{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, want := range files {
+		got, err := fs.ReadFile(checkedOut, name)
+		if err != nil {
+			t.Errorf("Could not read %q: %v", name, err)
+			continue
+		}
+
+		if string(got) != want {
+			t.Errorf("Incorrect content for %q (got %q, want %q)", name, got, want)
+		}
+	}
+
+	if _, err := fs.Stat(checkedOut, ".git"); err == nil {
+		t.Error("Unexpected .git directory")
+	}
+}
+
+func TestCheckoutLimits(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"action.yml":   "name: example",
+		"src/index.js": "console.log('Hello world!');",
+	}
+
+	commit := commitFiles(t, files)
+
+	type testCase struct {
+		limits Limits
+		ok     bool
+	}
+
+	testCases := map[string]testCase{
+		"no limits": {
+			limits: Limits{},
+			ok:     true,
+		},
+		"within limits": {
+			limits: Limits{Files: 2, Size: 41},
+			ok:     true,
+		},
+		"too many files": {
+			limits: Limits{Files: 1},
+			ok:     false,
+		},
+		"too large": {
+			limits: Limits{Size: 40},
+			ok:     false,
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := checkout(commit, tt.limits)
+			if tt.ok && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !tt.ok && err == nil {
+				t.Error("Unexpected success")
+			}
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("objects", func(t *testing.T) {
+		t.Parallel()
+
+		objects := limit(memory.NewStorage(), 16)
+
+		small := objects.NewEncodedObject()
+		small.SetType(plumbing.BlobObject)
+		small.SetSize(10)
+		if _, err := objects.SetEncodedObject(small); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if exceeded(objects) {
+			t.Error("Unexpectedly exceeded the limit")
+		}
+
+		large := objects.NewEncodedObject()
+		large.SetType(plumbing.BlobObject)
+		large.SetSize(10)
+		if _, err := objects.SetEncodedObject(large); err == nil {
+			t.Error("Unexpected success")
+		}
+
+		if !exceeded(objects) {
+			t.Error("Unexpectedly did not exceed the limit")
+		}
+	})
+
+	t.Run("packfile", func(t *testing.T) {
+		t.Parallel()
+
+		storage := filesystem.NewStorage(memfs.New(), cache.NewObjectLRUDefault())
+		objects := limit(storage, 16)
+
+		pw, ok := objects.(storer.PackfileWriter)
+		if !ok {
+			t.Fatal("Missing packfile writer")
+		}
+
+		w, err := pw.PackfileWriter()
+		if err != nil {
+			t.Fatalf("Could not get packfile writer: %v", err)
+		}
+
+		defer w.Close()
+
+		if _, err := w.Write(make([]byte, 10)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := w.Write(make([]byte, 10)); err == nil {
+			t.Error("Unexpected success")
+		}
+
+		if !exceeded(objects) {
+			t.Error("Unexpectedly did not exceed the limit")
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		t.Parallel()
+
+		storage := memory.NewStorage()
+		if objects := limit(storage, 0); objects != storage {
+			t.Error("Unexpected wrapper without a limit")
+		}
+	})
+}
+
+func commitFiles(t *testing.T, files map[string]string) *object.Commit {
+	t.Helper()
+
+	worktree := memfs.New()
+	repository, err := git.Init(memory.NewStorage(), worktree)
+	if err != nil {
+		t.Fatalf("Could not create repository: %v", err)
+	}
+
+	w, err := repository.Worktree()
+	if err != nil {
+		t.Fatalf("Could not get worktree: %v", err)
+	}
+
+	for name, content := range files {
+		if err := util.WriteFile(worktree, name, []byte(content), 0o644); err != nil {
+			t.Fatalf("Could not write %q: %v", name, err)
+		}
+
+		if _, err := w.Add(name); err != nil {
+			t.Fatalf("Could not add %q: %v", name, err)
+		}
+	}
+
+	opts := git.CommitOptions{
+		Author: &object.Signature{Name: "ghasum", Email: "ghasum@example.com"},
+	}
+
+	hash, err := w.Commit("Initial commit", &opts)
+	if err != nil {
+		t.Fatalf("Could not commit: %v", err)
+	}
+
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("Could not get commit: %v", err)
+	}
+
+	return commit
+}
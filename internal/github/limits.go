@@ -0,0 +1,150 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage"
+)
+
+// Limits bound the resources used to fetch a repository. A limit with the zero
+// value is not enforced.
+type Limits struct {
+	// Download is the maximum number of bytes of git objects fetched.
+	Download int64
+
+	// Files is the maximum number of files at the ref.
+	Files int
+
+	// Size is the maximum total size, in bytes, of the files at the ref.
+	Size int64
+}
+
+// DefaultLimits are generous limits that repositories of GitHub Actions should
+// never exceed.
+var DefaultLimits = Limits{
+	Download: 1 << 30,
+	Files:    100_000,
+	Size:     1 << 30,
+}
+
+// errDownloadLimit is the error when fetching exceeds the download limit.
+var errDownloadLimit = errors.New("download limit exceeded")
+
+// limitedStorer is a storage.Storer that errors when more than a given number of
+// bytes of objects are written to it.
+type limitedStorer struct {
+	storage.Storer
+
+	remaining int64
+	exceeded  bool
+}
+
+// limitedPackfileStorer is a limitedStorer for object stores that support
+// writing packfiles directly.
+type limitedPackfileStorer struct {
+	*limitedStorer
+}
+
+// limitedWriter is an io.WriteCloser that errors when more bytes are written to
+// it than are remaining for its limitedStorer.
+type limitedWriter struct {
+	io.WriteCloser
+
+	storer *limitedStorer
+}
+
+// limit returns an object store that errors when more than the given number of
+// bytes of objects are written to it. If max is zero the object store is
+// returned as is.
+func limit(objects storage.Storer, max int64) storage.Storer {
+	if max <= 0 {
+		return objects
+	}
+
+	limited := &limitedStorer{Storer: objects, remaining: max}
+	if _, ok := objects.(storer.PackfileWriter); ok {
+		return limitedPackfileStorer{limited}
+	}
+
+	return limited
+}
+
+// exceeded reports whether more bytes of objects were written to the given
+// object store than its limit allows.
+func exceeded(objects storage.Storer) bool {
+	switch s := objects.(type) {
+	case *limitedStorer:
+		return s.exceeded
+	case limitedPackfileStorer:
+		return s.exceeded
+	default:
+		return false
+	}
+}
+
+func (s *limitedStorer) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	if err := s.consume(obj.Size()); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return s.Storer.SetEncodedObject(obj)
+}
+
+func (s *limitedStorer) consume(n int64) error {
+	if n > s.remaining {
+		s.exceeded = true
+		return errDownloadLimit
+	}
+
+	s.remaining -= n
+	return nil
+}
+
+func (s limitedPackfileStorer) PackfileWriter() (io.WriteCloser, error) {
+	w, err := s.Storer.(storer.PackfileWriter).PackfileWriter()
+	if err != nil {
+		return nil, err
+	}
+
+	return limitedWriter{WriteCloser: w, storer: s.limitedStorer}, nil
+}
+
+func (w limitedWriter) Write(p []byte) (int, error) {
+	if err := w.storer.consume(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	return w.WriteCloser.Write(p)
+}
+
+// checkLimits returns an error if the files at a ref, given their count and
+// total size, exceed the given limits.
+func checkLimits(limits Limits, files int, size int64) error {
+	if limits.Files > 0 && files > limits.Files {
+		return fmt.Errorf("more than %d files", limits.Files)
+	}
+
+	if limits.Size > 0 && size > limits.Size {
+		return fmt.Errorf("files larger than %d bytes in total", limits.Size)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	cdxBOM struct {
+		BOMFormat   string         `json:"bomFormat"`
+		SpecVersion string         `json:"specVersion"`
+		Version     int            `json:"version"`
+		Metadata    cdxMetadata    `json:"metadata"`
+		Components  []cdxComponent `json:"components"`
+	}
+
+	cdxMetadata struct {
+		Timestamp string       `json:"timestamp,omitempty"`
+		Tools     cdxTools     `json:"tools"`
+		Component cdxComponent `json:"component"`
+	}
+
+	cdxTools struct {
+		Components []cdxComponent `json:"components"`
+	}
+
+	cdxComponent struct {
+		Type       string        `json:"type"`
+		BOMRef     string        `json:"bom-ref,omitempty"`
+		Group      string        `json:"group,omitempty"`
+		Name       string        `json:"name"`
+		Version    string        `json:"version,omitempty"`
+		PURL       string        `json:"purl,omitempty"`
+		Properties []cdxProperty `json:"properties,omitempty"`
+		Evidence   *cdxEvidence  `json:"evidence,omitempty"`
+	}
+
+	cdxProperty struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	cdxEvidence struct {
+		Occurrences []cdxOccurrence `json:"occurrences"`
+	}
+
+	cdxOccurrence struct {
+		Location string `json:"location"`
+	}
+)
+
+// cdxChecksumProperty is the name of the property holding the checksum of a
+// component.
+const cdxChecksumProperty = "ghasum:checksum"
+
+func encodeCycloneDX(doc *Document) ([]byte, error) {
+	bom := cdxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Tools: cdxTools{
+				Components: []cdxComponent{{
+					Type:    "application",
+					Name:    doc.Tool,
+					Version: doc.ToolVersion,
+				}},
+			},
+			Component: cdxComponent{
+				Type: "application",
+				Name: doc.Name,
+			},
+		},
+		Components: make([]cdxComponent, 0, len(doc.Components)),
+	}
+
+	if !doc.Created.IsZero() {
+		bom.Metadata.Timestamp = doc.Created.UTC().Format(time.RFC3339)
+	}
+
+	for _, component := range sorted(doc.Components) {
+		purl := component.PackageURL()
+		c := cdxComponent{
+			Type:    "application",
+			BOMRef:  purl,
+			Group:   component.Owner,
+			Name:    component.Project,
+			Version: component.Ref,
+			PURL:    purl,
+		}
+
+		if component.Checksum != "" {
+			c.Properties = []cdxProperty{{
+				Name:  cdxChecksumProperty,
+				Value: component.Checksum,
+			}}
+		}
+
+		if len(component.Locations) > 0 {
+			c.Evidence = &cdxEvidence{}
+			for _, location := range component.Locations {
+				c.Evidence.Occurrences = append(c.Evidence.Occurrences, cdxOccurrence{
+					Location: location,
+				})
+			}
+		}
+
+		bom.Components = append(bom.Components, c)
+	}
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode CycloneDX: %v", err)
+	}
+
+	return append(data, '\n'), nil
+}
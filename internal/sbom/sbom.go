@@ -0,0 +1,152 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Format represents a format of a software bill of materials.
+type Format int
+
+const (
+	// CycloneDX is the CycloneDX format, version 1.5, in JSON.
+	CycloneDX Format = iota
+
+	// SPDX is the SPDX format, version 2.3, in JSON.
+	SPDX
+)
+
+type (
+	// A Document is a software bill of materials of GitHub Actions.
+	Document struct {
+		// Name is the name of the subject of the document, for example the name of
+		// the repository using the GitHub Actions.
+		Name string
+
+		// Tool is the name of the tool that created the document.
+		Tool string
+
+		// ToolVersion is the version of the tool that created the document.
+		ToolVersion string
+
+		// Created is the time at which the document was created.
+		Created time.Time
+
+		// Components are the GitHub Actions described by the document.
+		Components []Component
+	}
+
+	// A Component is a GitHub Action described by a Document.
+	Component struct {
+		// Owner is the GitHub user or organization that owns the repository of
+		// the GitHub Action.
+		Owner string
+
+		// Project is the name of the GitHub repository (excluding the owner) of
+		// the GitHub Action.
+		Project string
+
+		// Ref is the git ref of the GitHub Action.
+		Ref string
+
+		// Checksum is the checksum of the GitHub Action computed by ghasum,
+		// including its tag, for example "h1:<digest>". It is a hash over the
+		// files of the GitHub Action rather than of an artifact, so it is not
+		// presented as a standard hash.
+		Checksum string
+
+		// Locations are the places where the GitHub Action is used, for example
+		// workflows or jobs.
+		Locations []string
+	}
+)
+
+var formats = map[Format]string{
+	CycloneDX: "cyclonedx",
+	SPDX:      "spdx",
+}
+
+// ErrUnknownFormat is the error used when a format is not known.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Encode writes the given document to w in the given format.
+func Encode(w io.Writer, doc *Document, format Format) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch format {
+	case CycloneDX:
+		data, err = encodeCycloneDX(doc)
+	case SPDX:
+		data, err = encodeSPDX(doc)
+	default:
+		err = ErrUnknownFormat
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("could not write SBOM: %v", err)
+	}
+
+	return nil
+}
+
+// ParseFormat parses the given name into a Format.
+func ParseFormat(name string) (Format, error) {
+	for format, formatName := range formats {
+		if strings.EqualFold(name, formatName) {
+			return format, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%v %q", ErrUnknownFormat, name)
+}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	return formats[f]
+}
+
+// PackageURL returns the package URL (purl) identifying the given component.
+func (c *Component) PackageURL() string {
+	return fmt.Sprintf(
+		"pkg:github/%s/%s@%s",
+		url.PathEscape(strings.ToLower(c.Owner)),
+		url.PathEscape(strings.ToLower(c.Project)),
+		url.PathEscape(c.Ref),
+	)
+}
+
+// sorted returns a copy of the given components ordered by package URL.
+func sorted(components []Component) []Component {
+	result := slices.Clone(components)
+	slices.SortFunc(result, func(a, b Component) int {
+		return strings.Compare(a.PackageURL(), b.PackageURL())
+	})
+
+	return result
+}
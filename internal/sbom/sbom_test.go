@@ -0,0 +1,241 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testDocument = Document{
+	Name:        "example",
+	Tool:        "ghasum",
+	ToolVersion: "1.2.3",
+	Created:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	Components: []Component{
+		{
+			Owner:     "actions",
+			Project:   "setup-go",
+			Ref:       "v5.0.0",
+			Checksum:  "h1:3q0=",
+			Locations: []string{"check.yml:lint"},
+		},
+		{
+			Owner:     "actions",
+			Project:   "checkout",
+			Ref:       "v4",
+			Checksum:  "h2:vu8=",
+			Locations: []string{"check.yml:lint", "check.yml:test"},
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CycloneDX", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := Encode(&out, &testDocument, CycloneDX); err != nil {
+			t.Fatalf("Unexpected error: %+v", err)
+		}
+
+		var got cdxBOM
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("Output is not valid JSON: %+v", err)
+		}
+
+		if got, want := got.BOMFormat, "CycloneDX"; got != want {
+			t.Errorf("Incorrect bomFormat (got %q, want %q)", got, want)
+		}
+
+		if got, want := got.Metadata.Timestamp, "2024-01-02T03:04:05Z"; got != want {
+			t.Errorf("Incorrect timestamp (got %q, want %q)", got, want)
+		}
+
+		if got, want := len(got.Components), 2; got != want {
+			t.Fatalf("Incorrect component count (got %d, want %d)", got, want)
+		}
+
+		component := got.Components[0]
+		if got, want := component.PURL, "pkg:github/actions/checkout@v4"; got != want {
+			t.Errorf("Incorrect purl (got %q, want %q)", got, want)
+		}
+
+		if bytes.Contains(out.Bytes(), []byte(`"hashes"`)) {
+			t.Error("Unexpected hashes, checksums are not standard hashes")
+		}
+
+		if got, want := component.Properties[0], (cdxProperty{Name: "ghasum:checksum", Value: "h2:vu8="}); got != want {
+			t.Errorf("Incorrect checksum property (got %v, want %v)", got, want)
+		}
+
+		if got, want := len(component.Evidence.Occurrences), 2; got != want {
+			t.Errorf("Incorrect occurrence count (got %d, want %d)", got, want)
+		}
+	})
+
+	t.Run("SPDX", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := Encode(&out, &testDocument, SPDX); err != nil {
+			t.Fatalf("Unexpected error: %+v", err)
+		}
+
+		var got spdxDocument
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("Output is not valid JSON: %+v", err)
+		}
+
+		if got, want := got.SPDXVersion, "SPDX-2.3"; got != want {
+			t.Errorf("Incorrect spdxVersion (got %q, want %q)", got, want)
+		}
+
+		if got, want := len(got.Packages), 3; got != want {
+			t.Fatalf("Incorrect package count (got %d, want %d)", got, want)
+		}
+
+		pkg := got.Packages[2]
+		if got, want := pkg.Name, "actions/setup-go"; got != want {
+			t.Errorf("Incorrect name (got %q, want %q)", got, want)
+		}
+
+		if bytes.Contains(out.Bytes(), []byte(`"checksums"`)) {
+			t.Error("Unexpected checksums, checksums are not standard hashes")
+		}
+
+		if got, want := pkg.Comment, "ghasum checksum: h1:3q0=\nUsed in: check.yml:lint"; got != want {
+			t.Errorf("Incorrect comment (got %q, want %q)", got, want)
+		}
+
+		if got, want := len(got.Relationships), 3; got != want {
+			t.Errorf("Incorrect relationship count (got %d, want %d)", got, want)
+		}
+	})
+
+	t.Run("SPDX without creation time", func(t *testing.T) {
+		t.Parallel()
+
+		doc := testDocument
+		doc.Created = time.Time{}
+
+		var out bytes.Buffer
+		if err := Encode(&out, &doc, SPDX); err == nil {
+			t.Error("Expected an error, got none")
+		}
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		for _, format := range []Format{CycloneDX, SPDX} {
+			var a, b bytes.Buffer
+			if err := Encode(&a, &testDocument, format); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			if err := Encode(&b, &testDocument, format); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			if !bytes.Equal(a.Bytes(), b.Bytes()) {
+				t.Errorf("Output for %s is not deterministic", format)
+			}
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		err := Encode(&out, &testDocument, Format(42))
+		if !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Unexpected error (got %v, want %v)", err, ErrUnknownFormat)
+		}
+	})
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		name string
+		want Format
+	}{
+		"cyclonedx":  {name: "cyclonedx", want: CycloneDX},
+		"spdx":       {name: "spdx", want: SPDX},
+		"mixed case": {name: "CycloneDX", want: CycloneDX},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFormat(tt.name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Incorrect format (got %v, want %v)", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := ParseFormat("xml"); err == nil {
+			t.Error("Expected an error, got none")
+		}
+	})
+}
+
+func TestPackageURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		component Component
+		want      string
+	}{
+		"simple": {
+			component: Component{Owner: "actions", Project: "checkout", Ref: "v4"},
+			want:      "pkg:github/actions/checkout@v4",
+		},
+		"uppercase": {
+			component: Component{Owner: "Actions", Project: "Checkout", Ref: "V4"},
+			want:      "pkg:github/actions/checkout@V4",
+		},
+		"slash in ref": {
+			component: Component{Owner: "actions", Project: "checkout", Ref: "release/v4"},
+			want:      "pkg:github/actions/checkout@release%2Fv4",
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.component.PackageURL(); got != tt.want {
+				t.Errorf("Incorrect purl (got %q, want %q)", got, tt.want)
+			}
+		})
+	}
+}
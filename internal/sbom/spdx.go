@@ -0,0 +1,161 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	spdxDocument struct {
+		SPDXVersion       string             `json:"spdxVersion"`
+		DataLicense       string             `json:"dataLicense"`
+		SPDXID            string             `json:"SPDXID"`
+		Name              string             `json:"name"`
+		DocumentNamespace string             `json:"documentNamespace"`
+		CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+		Packages          []spdxPackage      `json:"packages"`
+		Relationships     []spdxRelationship `json:"relationships"`
+	}
+
+	spdxCreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	}
+
+	spdxPackage struct {
+		SPDXID           string            `json:"SPDXID"`
+		Name             string            `json:"name"`
+		VersionInfo      string            `json:"versionInfo,omitempty"`
+		DownloadLocation string            `json:"downloadLocation"`
+		FilesAnalyzed    bool              `json:"filesAnalyzed"`
+		ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+		Comment          string            `json:"comment,omitempty"`
+	}
+
+	spdxExternalRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+
+	spdxRelationship struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelationshipType   string `json:"relationshipType"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+	}
+)
+
+const (
+	spdxDocumentID = "SPDXRef-DOCUMENT"
+	spdxRootID     = "SPDXRef-Repository"
+)
+
+func encodeSPDX(doc *Document) ([]byte, error) {
+	if doc.Created.IsZero() {
+		return nil, errors.New("could not encode SPDX: creation time is required")
+	}
+
+	created := doc.Created.UTC().Format(time.RFC3339)
+	bom := spdxDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      spdxDocumentID,
+		Name:        doc.Name,
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{fmt.Sprintf("Tool: %s-%s", doc.Tool, doc.ToolVersion)},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           spdxRootID,
+			Name:             doc.Name,
+			DownloadLocation: "NOASSERTION",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      spdxDocumentID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: spdxRootID,
+		}},
+	}
+
+	identity := sha256.New()
+	identity.Write([]byte(doc.Name + "\n" + created + "\n"))
+
+	for i, component := range sorted(doc.Components) {
+		purl := component.PackageURL()
+		identity.Write([]byte(purl + "\n"))
+
+		p := spdxPackage{
+			SPDXID:           fmt.Sprintf("SPDXRef-Action-%d", i+1),
+			Name:             fmt.Sprintf("%s/%s", component.Owner, component.Project),
+			VersionInfo:      component.Ref,
+			DownloadLocation: fmt.Sprintf("git+https://github.com/%s/%s@%s", component.Owner, component.Project, component.Ref),
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl,
+			}},
+		}
+
+		comment := make([]string, 0, 2)
+		if component.Checksum != "" {
+			identity.Write([]byte(component.Checksum + "\n"))
+			comment = append(comment, "ghasum checksum: "+component.Checksum)
+		}
+
+		if len(component.Locations) > 0 {
+			comment = append(comment, "Used in: "+strings.Join(component.Locations, ", "))
+		}
+
+		p.Comment = strings.Join(comment, "\n")
+
+		bom.Packages = append(bom.Packages, p)
+		bom.Relationships = append(bom.Relationships, spdxRelationship{
+			SPDXElementID:      spdxRootID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: p.SPDXID,
+		})
+	}
+
+	bom.DocumentNamespace = fmt.Sprintf(
+		"https://spdx.org/spdxdocs/%s-%x",
+		namespaceName(doc.Name),
+		identity.Sum(nil)[:16],
+	)
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode SPDX: %v", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// namespaceName returns the given name with all characters that are not safe
+// to use in the document namespace replaced.
+func namespaceName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+
+		return '-'
+	}, name)
+}
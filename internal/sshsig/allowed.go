@@ -0,0 +1,96 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshsig
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// An AllowedSigner is a signer trusted to make signatures, as listed in an
+// allowed signers file (see "ALLOWED SIGNERS" in ssh-keygen(1)).
+type AllowedSigner struct {
+	// Principals are the identities of the signer, for example email addresses.
+	Principals []string
+
+	// PublicKey is the public key of the signer.
+	PublicKey ssh.PublicKey
+
+	// Namespaces are the namespaces for which the signer is trusted. If empty
+	// the signer is trusted for any namespace.
+	Namespaces []string
+}
+
+// ParseAllowedSigners parses the content of an allowed signers file. Empty lines
+// and lines starting with "#" are ignored. The "cert-authority", "valid-after",
+// and "valid-before" options are not supported and result in an error.
+func ParseAllowedSigners(data []byte) ([]AllowedSigner, error) {
+	signers := make([]AllowedSigner, 0)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		principals, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("missing public key on line %d", i+1)
+		}
+
+		publicKey, _, options, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key on line %d: %v", i+1, err)
+		}
+
+		signer := AllowedSigner{
+			Principals: strings.Split(principals, ","),
+			PublicKey:  publicKey,
+		}
+
+		for _, option := range options {
+			name, value, _ := strings.Cut(option, "=")
+			switch strings.ToLower(name) {
+			case "namespaces":
+				value = strings.Trim(value, `"`)
+				signer.Namespaces = strings.Split(value, ",")
+			default:
+				return nil, fmt.Errorf("unsupported option %q on line %d", name, i+1)
+			}
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+// String returns the principals of the signer.
+func (s AllowedSigner) String() string {
+	return strings.Join(s.Principals, ",")
+}
+
+// allows reports whether the signer is trusted to make signatures with the
+// given public key for the Namespace of this package.
+func (s AllowedSigner) allows(publicKey ssh.PublicKey) bool {
+	if !bytes.Equal(s.PublicKey.Marshal(), publicKey.Marshal()) {
+		return false
+	}
+
+	return len(s.Namespaces) == 0 || slices.Contains(s.Namespaces, Namespace)
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshsig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Namespace is the namespace of the signatures created and verified by this
+// package, which prevents signatures for other purposes from being accepted.
+const Namespace = "ghasum"
+
+const (
+	armorStart = "-----BEGIN SSH SIGNATURE-----"
+	armorEnd   = "-----END SSH SIGNATURE-----"
+	armorWidth = 70
+
+	hashSha256 = "sha256"
+	hashSha512 = "sha512"
+
+	magic   = "SSHSIG"
+	version = 1
+)
+
+var (
+	// ErrSignature is the error used when a signature is malformed.
+	ErrSignature = errors.New("malformed signature")
+
+	// ErrUntrusted is the error used when a signature is not made by a trusted
+	// signer.
+	ErrUntrusted = errors.New("signature not made by a trusted signer")
+
+	// ErrInvalid is the error used when a signature does not match the signed
+	// message.
+	ErrInvalid = errors.New("signature does not match")
+)
+
+type (
+	// blob is the binary representation of a signature, following the magic
+	// preamble.
+	blob struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+
+	// signedData is the binary representation of the data that is signed,
+	// following the magic preamble.
+	signedData struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}
+)
+
+// Armor encodes the given signature in the armored format used by ssh-keygen.
+func Armor(signature []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(signature)
+
+	var sb strings.Builder
+	sb.WriteString(armorStart)
+	sb.WriteRune('\n')
+	for len(encoded) > armorWidth {
+		sb.WriteString(encoded[:armorWidth])
+		sb.WriteRune('\n')
+		encoded = encoded[armorWidth:]
+	}
+
+	sb.WriteString(encoded)
+	sb.WriteRune('\n')
+	sb.WriteString(armorEnd)
+	sb.WriteRune('\n')
+
+	return []byte(sb.String())
+}
+
+// Sign signs the given message using the given signer. The signature is
+// returned in binary form, see Armor for the textual form.
+func Sign(signer ssh.Signer, message []byte) ([]byte, error) {
+	data := signable(hashSha512, message)
+
+	var (
+		signature *ssh.Signature
+		err       error
+	)
+
+	publicKey := signer.PublicKey()
+	if algoSigner, ok := signer.(ssh.AlgorithmSigner); ok && publicKey.Type() == ssh.KeyAlgoRSA {
+		signature, err = algoSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not sign: %v", err)
+	}
+
+	b := blob{
+		Version:       version,
+		PublicKey:     publicKey.Marshal(),
+		Namespace:     Namespace,
+		HashAlgorithm: hashSha512,
+		Signature:     ssh.Marshal(signature),
+	}
+
+	return append([]byte(magic), ssh.Marshal(b)...), nil
+}
+
+// Unarmor decodes a signature in the armored format used by ssh-keygen.
+func Unarmor(armored []byte) ([]byte, error) {
+	content := strings.TrimSpace(string(armored))
+	content, ok := strings.CutPrefix(content, armorStart)
+	if !ok {
+		return nil, ErrSignature
+	}
+
+	content, ok = strings.CutSuffix(content, armorEnd)
+	if !ok {
+		return nil, ErrSignature
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+	if err != nil {
+		return nil, ErrSignature
+	}
+
+	return signature, nil
+}
+
+// Verify verifies that the given signature, in binary form, is a signature for
+// the given message made by one of the given signers. It returns the signer
+// that made the signature.
+func Verify(signature, message []byte, signers []AllowedSigner) (AllowedSigner, error) {
+	raw, ok := bytes.CutPrefix(signature, []byte(magic))
+	if !ok {
+		return AllowedSigner{}, ErrSignature
+	}
+
+	var b blob
+	if err := ssh.Unmarshal(raw, &b); err != nil || b.Version != version {
+		return AllowedSigner{}, ErrSignature
+	}
+
+	if b.Namespace != Namespace {
+		return AllowedSigner{}, fmt.Errorf("%v: unexpected namespace %q", ErrSignature, b.Namespace)
+	}
+
+	if b.HashAlgorithm != hashSha256 && b.HashAlgorithm != hashSha512 {
+		return AllowedSigner{}, fmt.Errorf("%v: unsupported hash algorithm %q", ErrSignature, b.HashAlgorithm)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(b.PublicKey)
+	if err != nil {
+		return AllowedSigner{}, fmt.Errorf("%v: %v", ErrSignature, err)
+	}
+
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(b.Signature, &sig); err != nil {
+		return AllowedSigner{}, ErrSignature
+	}
+
+	if sig.Format == ssh.KeyAlgoRSA {
+		return AllowedSigner{}, fmt.Errorf("%v: unsupported signature algorithm %q", ErrSignature, sig.Format)
+	}
+
+	if err := publicKey.Verify(signable(b.HashAlgorithm, message), &sig); err != nil {
+		return AllowedSigner{}, ErrInvalid
+	}
+
+	for _, signer := range signers {
+		if signer.allows(publicKey) {
+			return signer, nil
+		}
+	}
+
+	return AllowedSigner{}, ErrUntrusted
+}
+
+// signable returns the data to sign for the given message, hashed using the
+// given hash algorithm.
+func signable(hashAlgorithm string, message []byte) []byte {
+	var hash []byte
+	if hashAlgorithm == hashSha256 {
+		sum := sha256.Sum256(message)
+		hash = sum[:]
+	} else {
+		sum := sha512.Sum512(message)
+		hash = sum[:]
+	}
+
+	data := signedData{
+		Namespace:     Namespace,
+		HashAlgorithm: hashAlgorithm,
+		Hash:          hash,
+	}
+
+	return append([]byte(magic), ssh.Marshal(data)...)
+}
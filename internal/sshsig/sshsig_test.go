@@ -0,0 +1,298 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// keygenSigner is an allowed signer for the key used to create keygenSig.
+	keygenSigner = "test@example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID6DXR71h7laYw1nl6zjA+i3yB2I3U0aPcvIedGewBUo test"
+
+	// keygenSig is a signature for keygenMsg created with "ssh-keygen -Y sign".
+	keygenSig = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgPoNdHvWHuVpjDWeXrOMD6LfIHY
+jdTRo9y8h50Z7AFSgAAAAGZ2hhc3VtAAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1lZDI1
+NTE5AAAAQAU8KF3zB+e1+oGuWN+NA+ngv4kCwqeWfkPTelamAaU5yzSUYEIT1bX0Tw26a8
+FhdO2DU2AIJls75c8KtxaqqQA=
+-----END SSH SIGNATURE-----
+`
+
+	// keygenMsg is the message signed by keygenSig.
+	keygenMsg = "hello\n"
+)
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	signer, allowed := newSigner(t)
+	_, other := newSigner(t)
+
+	correct := func(message []byte) bool {
+		signature, err := Sign(signer, message)
+		if err != nil {
+			return false
+		}
+
+		got, err := Verify(signature, message, allowed)
+		return err == nil && got.String() == allowed[0].String()
+	}
+
+	if err := quick.Check(correct, nil); err != nil {
+		t.Errorf("verify(sign(x)) failed for: %v", err)
+	}
+
+	tampered := func(message []byte) bool {
+		signature, err := Sign(signer, message)
+		if err != nil {
+			return false
+		}
+
+		message = append(message, 'x')
+		_, err = Verify(signature, message, allowed)
+		return errors.Is(err, ErrInvalid)
+	}
+
+	if err := quick.Check(tampered, nil); err != nil {
+		t.Errorf("verify(sign(x)) succeeded for a tampered message: %v", err)
+	}
+
+	untrusted := func(message []byte) bool {
+		signature, err := Sign(signer, message)
+		if err != nil {
+			return false
+		}
+
+		_, err = Verify(signature, message, other)
+		return errors.Is(err, ErrUntrusted)
+	}
+
+	if err := quick.Check(untrusted, nil); err != nil {
+		t.Errorf("verify(sign(x)) succeeded for an untrusted signer: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("ssh-keygen", func(t *testing.T) {
+		t.Parallel()
+
+		signers, err := ParseAllowedSigners([]byte(keygenSigner))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		signature, err := Unarmor([]byte(keygenSig))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		got, err := Verify(signature, []byte(keygenMsg), signers)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if got, want := got.String(), "test@example.com"; got != want {
+			t.Errorf("Unexpected signer (got %q, want %q)", got, want)
+		}
+	})
+
+	t.Run("Other namespace", func(t *testing.T) {
+		t.Parallel()
+
+		signers, err := ParseAllowedSigners([]byte(`test@example.com namespaces="git,file" ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID6DXR71h7laYw1nl6zjA+i3yB2I3U0aPcvIedGewBUo`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		signature, err := Unarmor([]byte(keygenSig))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := Verify(signature, []byte(keygenMsg), signers); !errors.Is(err, ErrUntrusted) {
+			t.Errorf("Unexpected error (got %v, want %v)", err, ErrUntrusted)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := map[string][]byte{
+			"empty":     {},
+			"no magic":  []byte("not a signature"),
+			"truncated": []byte("SSHSIG\x00\x00"),
+		}
+
+		for name, signature := range testCases {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := Verify(signature, nil, nil); !errors.Is(err, ErrSignature) {
+					t.Errorf("Unexpected error (got %v, want %v)", err, ErrSignature)
+				}
+			})
+		}
+	})
+}
+
+func TestArmor(t *testing.T) {
+	t.Parallel()
+
+	correct := func(signature []byte) bool {
+		got, err := Unarmor(Armor(signature))
+		return err == nil && bytes.Equal(got, signature)
+	}
+
+	if err := quick.Check(correct, nil); err != nil {
+		t.Errorf("unarmor(armor(x)) != x for: %v", err)
+	}
+
+	signature, err := Unarmor([]byte(keygenSig))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got, want := string(Armor(signature)), keygenSig; got != want {
+		t.Errorf("Unexpected armor (got %q, want %q)", got, want)
+	}
+}
+
+func TestUnarmor(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"empty":     "",
+		"no start":  "U1NIU0lH\n-----END SSH SIGNATURE-----\n",
+		"no end":    "-----BEGIN SSH SIGNATURE-----\nU1NIU0lH\n",
+		"no base64": "-----BEGIN SSH SIGNATURE-----\n!!!\n-----END SSH SIGNATURE-----\n",
+	}
+
+	for name, armored := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := Unarmor([]byte(armored)); !errors.Is(err, ErrSignature) {
+				t.Errorf("Unexpected error (got %v, want %v)", err, ErrSignature)
+			}
+		})
+	}
+}
+
+func TestParseAllowedSigners(t *testing.T) {
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			content    string
+			principals []string
+			namespaces []string
+		}
+
+		testCases := map[string]TestCase{
+			"single principal": {
+				content:    keygenSigner,
+				principals: []string{"test@example.com"},
+			},
+			"multiple principals": {
+				content:    "a@example.com,b@example.com " + keygenSigner[len("test@example.com "):],
+				principals: []string{"a@example.com", "b@example.com"},
+			},
+			"comments and blank lines": {
+				content:    "# comment\n\n" + keygenSigner + "\n",
+				principals: []string{"test@example.com"},
+			},
+			"namespaces": {
+				content:    `test@example.com namespaces="ghasum" ` + keygenSigner[len("test@example.com "):],
+				principals: []string{"test@example.com"},
+				namespaces: []string{"ghasum"},
+			},
+		}
+
+		for name, tt := range testCases {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				signers, err := ParseAllowedSigners([]byte(tt.content))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if got, want := len(signers), 1; got != want {
+					t.Fatalf("Unexpected number of signers (got %d, want %d)", got, want)
+				}
+
+				signer := signers[0]
+				if got, want := signer.Principals, tt.principals; !slices.Equal(got, want) {
+					t.Errorf("Unexpected principals (got %v, want %v)", got, want)
+				}
+
+				if got, want := signer.Namespaces, tt.namespaces; !slices.Equal(got, want) {
+					t.Errorf("Unexpected namespaces (got %v, want %v)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := map[string]string{
+			"missing key":    "test@example.com",
+			"invalid key":    "test@example.com ssh-ed25519 foobar",
+			"cert-authority": "test@example.com cert-authority " + keygenSigner[len("test@example.com "):],
+		}
+
+		for name, content := range testCases {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := ParseAllowedSigners([]byte(content)); err == nil {
+					t.Error("Expected an error, got none")
+				}
+			})
+		}
+	})
+}
+
+func newSigner(t *testing.T) (ssh.Signer, []AllowedSigner) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("Could not create signer: %v", err)
+	}
+
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Could not create public key: %v", err)
+	}
+
+	allowed := []AllowedSigner{{Principals: []string{"test"}, PublicKey: sshKey}}
+	return signer, allowed
+}
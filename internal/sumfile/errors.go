@@ -14,7 +14,12 @@
 
 package sumfile
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 var (
 	// ErrCorrupted is the error when a checksum file is corrupted.
@@ -37,3 +42,99 @@ var (
 	// checksum file.
 	ErrVersion = errors.New("version error")
 )
+
+// An Error is a problem at a specific position in a checksum file.
+type Error struct {
+	// File is the name of the checksum file, if known.
+	File string
+
+	// Line is the line number of the problem, starting at 1.
+	Line int
+
+	// Column is the column number of the problem in bytes, starting at 1.
+	Column int
+
+	// Kind is the kind of problem, one of ErrDuplicate, ErrHeaders, ErrMissing,
+	// ErrSyntax, or ErrVersion.
+	Kind error
+
+	// Text is the offending text, if any.
+	Text string
+
+	// Message describes the problem.
+	Message string
+}
+
+// An ErrorList is a list of problems in a checksum file, ordered by position.
+type ErrorList []*Error
+
+// Error returns the problem in the format "file:line:column: message", where
+// the file is omitted if it is not known.
+func (e *Error) Error() string {
+	position := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.File != "" {
+		position = fmt.Sprintf("%s:%s", e.File, position)
+	}
+
+	return fmt.Sprintf("%s: %v: %s", position, e.Kind, e.Message)
+}
+
+// Unwrap returns the kind of the problem.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Error returns all problems in the list, one per line.
+func (l ErrorList) Error() string {
+	lines := make([]string, len(l))
+	for i, err := range l {
+		lines[i] = err.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns all problems in the list.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// SetFile sets the name of the checksum file of all problems in the list.
+func (l ErrorList) SetFile(name string) {
+	for _, err := range l {
+		err.File = name
+	}
+}
+
+// add adds a problem of the given kind at the given position to the list.
+func (l *ErrorList) add(line, column int, kind error, text, message string) {
+	*l = append(*l, &Error{
+		Line:    line,
+		Column:  column,
+		Kind:    kind,
+		Text:    text,
+		Message: message,
+	})
+}
+
+// err returns the list as an error ordered by position, or nil if it is empty.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(l, func(a, b *Error) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+
+		return a.Column - b.Column
+	})
+
+	return l
+}
@@ -17,6 +17,7 @@ package sumfile
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -29,14 +30,53 @@ type Entry struct {
 	// ID is the identifier for the entry. Can have any number of parts but must
 	// not be empty.
 	ID []string
+
+	// Attributes are additional properties of the entry, keyed by name. They are
+	// only supported by Version2 and later.
+	Attributes map[string]string
+
+	// Comments are the lines of the comment attached to the entry, without the
+	// comment marker. They are only supported by Version3 and later.
+	Comments []string
+}
+
+// A Document represents the content of a checksum file.
+type Document struct {
+	// Version is the version of the checksum file.
+	Version Version
+
+	// Headers are the headers of the checksum file, keyed by name, except for
+	// the version header.
+	Headers map[string]string
+
+	// Entries are the checksum entries in the checksum file.
+	Entries []Entry
 }
 
 // Decode parses the given checksum file content into Entries. This will error
 // if there is a syntax error in the checksum file or if the checksum file is
 // otherwise corrupted (for example multiple checksum directives for one Entry).
+// Both LF and CRLF line endings are accepted.
 func Decode(stored string) ([]Entry, error) {
-	_, entries, err := parseFile(stored)
-	return entries, err
+	doc, err := DecodeDocument(stored)
+	return doc.Entries, err
+}
+
+// DecodeDocument parses the given checksum file content into a Document. This
+// errors in the same cases as Decode. If the headers can be parsed the version
+// and headers are included in the Document even if there is an error.
+func DecodeDocument(stored string) (Document, error) {
+	headers, entries, err := parseFile(stored)
+
+	doc := Document{Entries: entries}
+	if headers != nil {
+		doc.Version, _ = extractVersion(headers)
+
+		delete(headers, "version")
+		doc.Headers = headers
+	}
+
+	return doc, err
 }
 
 // DecodeVersion parses the given checksum file content to extract the version.
@@ -53,95 +93,169 @@ func DecodeVersion(stored string) (Version, error) {
 // Encode encodes the given checksums according to the specification of the
 // given version.
 func Encode(version Version, checksums []Entry) (string, error) {
+	return EncodeDocument(Document{Version: version, Entries: checksums})
+}
+
+// EncodeDocument encodes the given Document according to the specification of
+// its version. The version header is written first, followed by the other
+// headers in order of their name.
+func EncodeDocument(doc Document) (string, error) {
 	var (
 		encoded string
 		err     error
 	)
 
-	switch version {
+	switch doc.Version {
 	case Version1:
-		encoded, err = encodeV1(checksums)
+		encoded, err = encodeV1(doc.Entries)
+	case Version2:
+		encoded, err = encodeV2(doc.Entries)
+	case Version3:
+		encoded, err = encodeV3(doc.Entries)
 	default:
-		err = unknownVersion(version)
+		err = unknownVersion(doc.Version)
+	}
+
+	headers, headersErr := encodeHeaders(doc.Headers)
+	if err == nil {
+		err = headersErr
 	}
 
-	return fmt.Sprintf("version %d\n\n%s", version, encoded), err
+	return fmt.Sprintf("version %d\n%s\n%s", doc.Version, headers, encoded), err
+}
+
+func encodeHeaders(headers map[string]string) (string, error) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		value := headers[name]
+		if name == "" || name == "version" || strings.ContainsAny(name, "\n ") {
+			err := fmt.Errorf("invalid header name %q", name)
+			return "", errors.Join(ErrHeaders, err)
+		}
+
+		if strings.Contains(value, "\n") {
+			err := fmt.Errorf("invalid value for header %q", name)
+			return "", errors.Join(ErrHeaders, err)
+		}
+
+		sb.WriteString(fmt.Sprintf("%s %s\n", name, value))
+	}
+
+	return sb.String(), nil
 }
 
 func parseFile(stored string) (map[string]string, []Entry, error) {
+	stored = strings.ReplaceAll(stored, "\r\n", "\n")
 	lines := strings.Split(stored, "\n")
-	headers, err := parseHeaders(lines)
-	if err != nil {
-		return nil, nil, err
-	}
+	headers, positions, n, errs := parseHeaders(lines)
 
 	version, err := extractVersion(headers)
 	if err != nil {
-		return headers, nil, err
+		if line, ok := positions["version"]; ok {
+			errs.add(line, len("version ")+1, ErrVersion, headers["version"], err.Error())
+		} else {
+			errs.add(1, 1, ErrVersion, "", err.Error())
+		}
 	}
 
-	if lines[len(lines)-1] != "" {
-		err = errors.New("missing final newline")
-		return headers, nil, errors.Join(ErrSyntax, err)
+	last := len(lines) - 1
+	if lines[last] != "" {
+		errs.add(last+1, len(lines[last])+1, ErrSyntax, lines[last], "missing final newline")
+		last = len(lines)
 	}
 
 	content := []string{}
-	if len(lines) > len(headers)+1 {
-		content = lines[len(headers)+1 : len(lines)-1]
+	if n+1 < last {
+		content = lines[n+1 : last]
 	}
 
-	var entries []Entry
-	switch version {
-	case Version1:
-		entries, err = decodeV1(content)
-	default:
-		err = unknownVersion(version)
+	var (
+		entries    []Entry
+		contentErr ErrorList
+	)
+
+	if err == nil {
+		switch version {
+		case Version1:
+			entries, contentErr = decodeV1(content, n+2)
+		case Version2:
+			entries, contentErr = decodeV2(content, n+2)
+		case Version3:
+			entries, contentErr = decodeV3(content, n+2)
+		default:
+			line := positions["version"]
+			message := fmt.Sprintf("unknown version %d", version)
+			errs.add(line, len("version ")+1, ErrVersion, headers["version"], message)
+		}
 	}
 
-	if err != nil {
-		return headers, entries, err
+	errs = append(errs, contentErr...)
+	if err := errs.err(); err != nil {
+		if errors.Is(err, ErrHeaders) {
+			headers = nil
+		}
+
+		return headers, nil, err
 	}
 
 	return headers, entries, nil
 }
 
-func parseHeaders(lines []string) (map[string]string, error) {
-	headers := make(map[string]string, 0)
+// parseHeaders parses the headers at the start of the given lines, returning
+// the headers, the line number of every header, and the number of lines that
+// make up the headers. Invalid headers are reported and omitted.
+func parseHeaders(lines []string) (map[string]string, map[string]int, int, ErrorList) {
+	var (
+		errs      ErrorList
+		headers   = make(map[string]string, 0)
+		positions = make(map[string]int, 0)
+	)
+
+	n := 0
 	for i, line := range lines {
 		if len(line) == 0 {
 			break
 		}
 
+		n += 1
+
 		j := strings.IndexRune(line, ' ')
 		if j == -1 {
-			err := fmt.Errorf("invalid header on line %d", i+1)
-			return nil, errors.Join(ErrHeaders, err)
+			errs.add(i+1, 1, ErrHeaders, line, fmt.Sprintf("invalid header %q", line))
+			continue
 		}
 
 		key := line[0:j]
 		value := line[j+1:]
-		if _, ok := headers[key]; ok {
-			err := fmt.Errorf("duplicate header %q on line %d", key, i+1)
-			return nil, errors.Join(ErrHeaders, err)
+		if first, ok := positions[key]; ok {
+			message := fmt.Sprintf("duplicate header %q, first on line %d", key, first)
+			errs.add(i+1, 1, ErrHeaders, key, message)
+			continue
 		}
 
 		headers[key] = value
+		positions[key] = i + 1
 	}
 
-	return headers, nil
+	return headers, positions, n, errs
 }
 
 func extractVersion(headers map[string]string) (Version, error) {
 	version, ok := headers["version"]
 	if !ok {
-		err := errors.New("version not found")
-		return 0, errors.Join(ErrVersion, err)
+		return 0, errors.New("version not found")
 	}
 
 	rawVersion, err := strconv.Atoi(version)
 	if err != nil {
-		err := errors.New("version not a number")
-		return 0, errors.Join(ErrVersion, err)
+		return 0, errors.New("version not a number")
 	}
 
 	return Version(rawVersion), nil
@@ -15,6 +15,9 @@
 package sumfile
 
 import (
+	"errors"
+	"maps"
+	"strings"
 	"testing"
 	"testing/quick"
 )
@@ -131,3 +134,298 @@ missing final newline`,
 		})
 	}
 }
+
+func TestDocumentHeaders(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Decode", func(t *testing.T) {
+		t.Parallel()
+
+		stored := `version 1
+generator ghasum v0.2.0
+owner team-x
+
+foo@bar checksum
+`
+
+		doc, err := DecodeDocument(stored)
+		if err != nil {
+			t.Fatalf("Unexpected error: %+v", err)
+		}
+
+		if got, want := doc.Version, Version1; got != want {
+			t.Errorf("Incorrect version (got %d, want %d)", got, want)
+		}
+
+		want := map[string]string{
+			"generator": "ghasum v0.2.0",
+			"owner":     "team-x",
+		}
+		if got := doc.Headers; !maps.Equal(got, want) {
+			t.Errorf("Incorrect headers (got %v, want %v)", got, want)
+		}
+
+		if got, want := len(doc.Entries), 1; got != want {
+			t.Errorf("Incorrect entry count (got %d, want %d)", got, want)
+		}
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		t.Parallel()
+
+		doc := Document{
+			Version: Version1,
+			Headers: map[string]string{
+				"owner":     "team-x",
+				"generator": "ghasum v0.2.0",
+			},
+			Entries: []Entry{
+				{ID: []string{"foo", "bar"}, Checksum: "checksum"},
+			},
+		}
+
+		encoded, err := EncodeDocument(doc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %+v", err)
+		}
+
+		want := `version 1
+generator ghasum v0.2.0
+owner team-x
+
+foo@bar checksum
+`
+		if got := encoded; got != want {
+			t.Errorf("Incorrect encoding (got %q, want %q)", got, want)
+		}
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		t.Parallel()
+
+		roundTrip := func(version Version, headers map[string]string) bool {
+			doc := Document{
+				Version: (version % VersionLatest) + 1, // normalize version
+				Headers: headers,
+			}
+
+			encoded, err := EncodeDocument(doc)
+			if err != nil {
+				return true
+			}
+
+			decoded, err := DecodeDocument(encoded)
+			if err != nil {
+				return false
+			}
+
+			return decoded.Version == doc.Version &&
+				maps.Equal(decoded.Headers, doc.Headers)
+		}
+
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Errorf("decode(encode(x)) errored for: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := map[string]map[string]string{
+			"empty name":    {"": "value"},
+			"version":       {"version": "3"},
+			"space in name": {"foo bar": "value"},
+			"newline name":  {"foo\nbar": "value"},
+			"newline value": {"foo": "bar\nbaz"},
+		}
+
+		for name, headers := range testCases {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				doc := Document{Version: VersionLatest, Headers: headers}
+				if _, err := EncodeDocument(doc); err == nil {
+					t.Fatal("Unexpected success")
+				}
+			})
+		}
+	})
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	type Position struct {
+		line   int
+		column int
+		kind   error
+	}
+
+	testCases := map[string]struct {
+		content string
+		want    []Position
+	}{
+		"missing version": {
+			content: "foo bar\n\n",
+			want: []Position{
+				{line: 1, column: 1, kind: ErrVersion},
+			},
+		},
+		"version not a number": {
+			content: "version one\n\n",
+			want: []Position{
+				{line: 1, column: 9, kind: ErrVersion},
+			},
+		},
+		"invalid header": {
+			content: "version 2\nfoo\n\n",
+			want: []Position{
+				{line: 2, column: 1, kind: ErrHeaders},
+			},
+		},
+		"duplicate header": {
+			content: "version 2\nfoo bar\nfoo baz\n\n",
+			want: []Position{
+				{line: 3, column: 1, kind: ErrHeaders},
+			},
+		},
+		"entries after multiple headers": {
+			content: "version 3\nfoo bar\n\nid h1:sum\nsyntax-error\n",
+			want: []Position{
+				{line: 5, column: 13, kind: ErrSyntax},
+			},
+		},
+		"untagged checksum": {
+			content: "version 2\n\nid checksum\n",
+			want: []Position{
+				{line: 3, column: 4, kind: ErrSyntax},
+			},
+		},
+		"invalid attribute": {
+			content: "version 2\n\nid h1:sum kind=action Foo=bar\n",
+			want: []Position{
+				{line: 3, column: 23, kind: ErrSyntax},
+			},
+		},
+		"missing id part": {
+			content: "version 1\n\nfoo@ sum\n",
+			want: []Position{
+				{line: 3, column: 5, kind: ErrMissing},
+			},
+		},
+		"duplicate entry": {
+			content: "version 1\n\nfoo sum\nfoo sum\n",
+			want: []Position{
+				{line: 4, column: 1, kind: ErrDuplicate},
+			},
+		},
+		"missing final newline": {
+			content: "version 1\n\nfoo sum",
+			want: []Position{
+				{line: 3, column: 8, kind: ErrSyntax},
+			},
+		},
+		"multiple problems": {
+			content: "version 2\nfoo\n\nid\nid h1:sum\nid h1:sum\n",
+			want: []Position{
+				{line: 2, column: 1, kind: ErrHeaders},
+				{line: 4, column: 3, kind: ErrSyntax},
+				{line: 6, column: 1, kind: ErrDuplicate},
+			},
+		},
+	}
+
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Decode(tt.content)
+			if err == nil {
+				t.Fatal("Unexpected success")
+			}
+
+			var errs ErrorList
+			if !errors.As(err, &errs) {
+				t.Fatalf("Unexpected error type %T", err)
+			}
+
+			if got, want := len(errs), len(tt.want); got != want {
+				t.Fatalf("Incorrect number of errors (got %d, want %d): %v", got, want, err)
+			}
+
+			for i, want := range tt.want {
+				got := errs[i]
+				if got.Line != want.line || got.Column != want.column {
+					t.Errorf("Incorrect position for error %d (got %d:%d, want %d:%d)", i, got.Line, got.Column, want.line, want.column)
+				}
+
+				if !errors.Is(got, want.kind) {
+					t.Errorf("Incorrect kind for error %d (got %v, want %v)", i, got.Kind, want.kind)
+				}
+			}
+		})
+	}
+
+	t.Run("DecodeVersion", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := DecodeVersion("version 1\n\nfoo\n")
+
+		var errs ErrorList
+		if !errors.As(err, &errs) {
+			t.Fatalf("Unexpected error type %T", err)
+		}
+
+		if got, want := errs[0].Line, 3; got != want {
+			t.Errorf("Incorrect line number (got %d, want %d)", got, want)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		err := Error{Line: 3, Column: 5, Kind: ErrSyntax, Message: "missing checksum"}
+		if got, want := err.Error(), "3:5: syntax error: missing checksum"; got != want {
+			t.Errorf("Incorrect message (got %q, want %q)", got, want)
+		}
+
+		err.File = "gha.sum"
+		if got, want := err.Error(), "gha.sum:3:5: syntax error: missing checksum"; got != want {
+			t.Errorf("Incorrect message (got %q, want %q)", got, want)
+		}
+	})
+}
+
+func TestDecodeCRLF(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"version 1": "version 1\r\n\r\nfoo bar\r\n",
+		"version 2": "version 2\r\nfoo bar\r\n\r\nfoo h1:bar kind=action\r\n",
+		"version 3": "version 3\r\n\r\n# comment\r\nfoo h1:bar\r\n",
+	}
+
+	for name, crlf := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeDocument(crlf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			want, err := DecodeDocument(strings.ReplaceAll(crlf, "\r\n", "\n"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			if got.Version != want.Version || !maps.Equal(got.Headers, want.Headers) {
+				t.Errorf("Incorrect headers (got %v, want %v)", got.Headers, want.Headers)
+			}
+
+			if !SetEqual(got.Entries, want.Entries) {
+				t.Errorf("Incorrect entries (got %v, want %v)", got.Entries, want.Entries)
+			}
+		})
+	}
+}
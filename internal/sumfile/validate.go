@@ -14,6 +14,11 @@
 
 package sumfile
 
+import (
+	"fmt"
+	"strings"
+)
+
 func hasMissing(entries []Entry) bool {
 	for _, entry := range entries {
 		if len(entry.Checksum) == 0 || len(entry.ID) == 0 {
@@ -47,3 +52,35 @@ func hasDuplicates(entries []Entry) bool {
 
 	return false
 }
+
+// checkDuplicates adds a problem to errs for every entry with the same id as
+// an earlier entry, where lines are the line numbers of the entries.
+func checkDuplicates(errs *ErrorList, entries []Entry, lines []int) {
+	seen := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		key := strings.Join(entry.ID, "")
+		if first, ok := seen[key]; ok {
+			id := strings.Join(entry.ID, "@")
+			message := fmt.Sprintf("%q also on line %d", id, first)
+			errs.add(lines[i], 1, ErrDuplicate, id, message)
+			continue
+		}
+
+		seen[key] = lines[i]
+	}
+}
+
+// decodeID splits the given id into its parts, adding a problem to errs for
+// every empty part. The id starts at the given column of the given line.
+func decodeID(errs *ErrorList, line, column int, id string) []string {
+	parts := strings.Split(id, "@")
+	for _, part := range parts {
+		if part == "" {
+			errs.add(line, column, ErrMissing, id, fmt.Sprintf("missing id part in %q", id))
+		}
+
+		column += len(part) + 1
+	}
+
+	return parts
+}
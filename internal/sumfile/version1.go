@@ -16,28 +16,48 @@ package sumfile
 
 import (
 	"errors"
-	"fmt"
 	"sort"
 	"strings"
 )
 
-func decodeV1(lines []string) ([]Entry, error) {
-	entries := make([]Entry, len(lines))
+func decodeV1(lines []string, first int) ([]Entry, ErrorList) {
+	var errs ErrorList
+
+	entries := make([]Entry, 0, len(lines))
+	numbers := make([]int, 0, len(lines))
 	for i, line := range lines {
+		lineno := first + i
+
 		// split "line" into "id[@id..]" "sum"
 		j := strings.IndexRune(line, ' ')
-		if j <= 0 || j >= len(line)-1 {
-			return nil, fmt.Errorf("%v on line %d", ErrSyntax, i+3)
+		switch {
+		case j == -1:
+			errs.add(lineno, len(line)+1, ErrSyntax, line, "missing checksum")
+			continue
+		case j == 0:
+			errs.add(lineno, 1, ErrSyntax, line, "missing id")
+			continue
+		case j == len(line)-1:
+			errs.add(lineno, j+2, ErrSyntax, line, "missing checksum")
+			continue
 		}
 
-		entries[i] = Entry{
-			ID:       strings.Split(line[:j], "@"),
-			Checksum: line[j+1:],
+		checksum := line[j+1:]
+		if k := strings.IndexRune(checksum, ' '); k != -1 {
+			errs.add(lineno, j+k+2, ErrSyntax, checksum, "unexpected space in checksum")
+			continue
 		}
+
+		entries = append(entries, Entry{
+			ID:       decodeID(&errs, lineno, 1, line[:j]),
+			Checksum: checksum,
+		})
+		numbers = append(numbers, lineno)
 	}
 
-	if err := validV1(entries); err != nil {
-		return nil, errors.Join(ErrCorrupted, err)
+	checkDuplicates(&errs, entries, numbers)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	return entries, nil
@@ -80,6 +100,14 @@ func validV1(entries []Entry) error {
 	}
 
 	for _, entry := range entries {
+		if len(entry.Attributes) > 0 {
+			return errors.New("attributes are not supported")
+		}
+
+		if len(entry.Comments) > 0 {
+			return errors.New("comments are not supported")
+		}
+
 		if strings.ContainsAny(entry.Checksum, "\n ") {
 			return ErrSyntax
 		}
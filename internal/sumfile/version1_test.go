@@ -15,7 +15,6 @@
 package sumfile
 
 import (
-	"fmt"
 	"slices"
 	"strings"
 	"testing"
@@ -33,7 +32,7 @@ func TestVersion1(t *testing.T) {
 		encoded, _ := encodeV1(entries)
 		lines := strings.Split(encoded, "\n")
 
-		decoded, err := decodeV1(lines[:len(lines)-1])
+		decoded, err := decodeV1(lines[:len(lines)-1], 3)
 		if err != nil {
 			return true // Ignore errors, tested separately
 		}
@@ -53,7 +52,7 @@ func TestVersion1(t *testing.T) {
 		encoded, _ := encodeV1(entries)
 		lines := strings.Split(encoded, "\n")
 
-		_, err := decodeV1(lines[:len(lines)-1])
+		_, err := decodeV1(lines[:len(lines)-1], 3)
 		return err == nil
 	}
 
@@ -116,7 +115,7 @@ func TestDecodeV1(t *testing.T) {
 
 		for _, tc := range testCases {
 			t.Run(tc.name, func(t *testing.T) {
-				got, err := decodeV1(tc.content)
+				got, err := decodeV1(tc.content, 3)
 				if err != nil {
 					t.Fatalf("Unexpected error: %+v", err)
 				}
@@ -185,13 +184,13 @@ func TestDecodeV1(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				t.Parallel()
 
-				_, err := decodeV1(tc.content)
+				_, err := decodeV1(tc.content, 3)
 				if err == nil {
 					t.Fatal("Unexpected success")
 				}
 
-				if got, want := err.Error(), fmt.Sprintf("line %d", tc.want); !strings.Contains(got, want) {
-					t.Errorf("Incorrect line number (got %q, want %q)", got, want)
+				if got, want := err[0].Line, tc.want; got != want {
+					t.Errorf("Incorrect line number (got %d, want %d)", got, want)
 				}
 			})
 		}
@@ -324,6 +323,26 @@ b bb
 					},
 				},
 			},
+			{
+				name: "attributes",
+				content: []Entry{
+					{
+						ID:         []string{"anything"},
+						Checksum:   "anything",
+						Attributes: map[string]string{"kind": "action"},
+					},
+				},
+			},
+			{
+				name: "comments",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "anything",
+						Comments: []string{"reviewed"},
+					},
+				},
+			},
 		}
 
 		for _, tc := range testCases {
@@ -0,0 +1,224 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sumfile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func decodeV2(lines []string, first int) ([]Entry, ErrorList) {
+	var errs ErrorList
+
+	entries := make([]Entry, 0, len(lines))
+	numbers := make([]int, 0, len(lines))
+	for i, line := range lines {
+		entry, ok := decodeEntryV2(&errs, line, first+i)
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, entry)
+		numbers = append(numbers, first+i)
+	}
+
+	checkDuplicates(&errs, entries, numbers)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return entries, nil
+}
+
+// decodeEntryV2 decodes the given line into an Entry, adding a problem to errs
+// for every problem in the line. The Entry is usable only if it returns true.
+func decodeEntryV2(errs *ErrorList, line string, lineno int) (Entry, bool) {
+	// split "line" into "id[@id..]" "sum" "[key=value..]"
+	fields := strings.Split(line, " ")
+	columns := make([]int, len(fields))
+	for i, column := 0, 1; i < len(fields); i++ {
+		columns[i] = column
+		column += len(fields[i]) + 1
+	}
+
+	if len(fields) < 2 {
+		errs.add(lineno, len(line)+1, ErrSyntax, line, "missing checksum")
+		return Entry{}, false
+	}
+
+	ok := true
+	for i, field := range fields {
+		if field != "" {
+			continue
+		}
+
+		switch {
+		case i == 0:
+			errs.add(lineno, 1, ErrSyntax, line, "missing id")
+		case i == 1 && len(fields) == 2:
+			errs.add(lineno, columns[i], ErrSyntax, line, "missing checksum")
+		default:
+			errs.add(lineno, columns[i]-1, ErrSyntax, line, "unexpected space")
+		}
+
+		ok = false
+	}
+
+	if !ok {
+		return Entry{}, false
+	}
+
+	count := len(*errs)
+	entry := Entry{
+		ID:       decodeID(errs, lineno, 1, fields[0]),
+		Checksum: fields[1],
+	}
+
+	tag, digest, found := strings.Cut(entry.Checksum, ":")
+	if !found || tag == "" || digest == "" {
+		message := fmt.Sprintf("invalid checksum %q, want <tag>:<digest>", entry.Checksum)
+		errs.add(lineno, columns[1], ErrSyntax, entry.Checksum, message)
+	}
+
+	for i, field := range fields[2:] {
+		column := columns[i+2]
+
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			errs.add(lineno, column, ErrSyntax, field, fmt.Sprintf("invalid attribute %q, want <key>=<value>", field))
+			continue
+		}
+
+		if !validAttributeKey(key) {
+			errs.add(lineno, column, ErrSyntax, key, fmt.Sprintf("invalid attribute name %q", key))
+		}
+
+		if value == "" {
+			errs.add(lineno, column+len(key)+1, ErrSyntax, field, fmt.Sprintf("missing value for attribute %q", key))
+		}
+
+		if entry.Attributes == nil {
+			entry.Attributes = make(map[string]string)
+		}
+
+		if _, ok := entry.Attributes[key]; ok {
+			errs.add(lineno, column, ErrSyntax, key, fmt.Sprintf("duplicate attribute %q", key))
+		}
+
+		entry.Attributes[key] = value
+	}
+
+	return entry, len(*errs) == count
+}
+
+func encodeV2(entries []Entry) (string, error) {
+	if err := validV2(entries); err != nil {
+		return "", errors.Join(ErrCorrupted, err)
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = encodeEntryV2(entry)
+	}
+
+	sort.Strings(lines)
+	return strings.Join(lines, ""), nil
+}
+
+func encodeEntryV2(entry Entry) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(entry.ID, "@"))
+	sb.WriteRune(' ')
+	sb.WriteString(entry.Checksum)
+
+	keys := make([]string, 0, len(entry.Attributes))
+	for key := range entry.Attributes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		sb.WriteRune(' ')
+		sb.WriteString(key)
+		sb.WriteRune('=')
+		sb.WriteString(entry.Attributes[key])
+	}
+
+	sb.WriteRune('\n')
+	return sb.String()
+}
+
+func validV2(entries []Entry) error {
+	for _, entry := range entries {
+		if len(entry.Comments) > 0 {
+			return errors.New("comments are not supported")
+		}
+	}
+
+	return validEntriesV2(entries)
+}
+
+func validEntriesV2(entries []Entry) error {
+	if hasDuplicates(entries) {
+		return ErrDuplicate
+	}
+
+	if hasMissing(entries) {
+		return ErrMissing
+	}
+
+	for _, entry := range entries {
+		tag, digest, ok := strings.Cut(entry.Checksum, ":")
+		if !ok || tag == "" || digest == "" {
+			return ErrSyntax
+		}
+
+		if strings.ContainsAny(entry.Checksum, "\n ") {
+			return ErrSyntax
+		}
+
+		if strings.ContainsAny(strings.Join(entry.ID, ""), "\n @") {
+			return ErrSyntax
+		}
+
+		for key, value := range entry.Attributes {
+			if !validAttributeKey(key) || value == "" {
+				return ErrSyntax
+			}
+
+			if strings.ContainsAny(value, "\n ") {
+				return ErrSyntax
+			}
+		}
+	}
+
+	return nil
+}
+
+func validAttributeKey(key string) bool {
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
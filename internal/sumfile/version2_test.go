@@ -0,0 +1,443 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sumfile
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestVersion2(t *testing.T) {
+	t.Parallel()
+
+	correct := func(entries []Entry) bool {
+		if err := validV2(entries); err != nil {
+			return true
+		}
+
+		encoded, _ := encodeV2(entries)
+		lines := strings.Split(encoded, "\n")
+
+		decoded, err := decodeV2(lines[:len(lines)-1], 3)
+		if err != nil {
+			return true // Ignore errors, tested separately
+		}
+
+		return SetEqual(decoded, entries)
+	}
+
+	if err := quick.Check(correct, nil); err != nil {
+		t.Errorf("decode(encode(x)) != x for: %v", err)
+	}
+
+	decodable := func(entries []Entry) bool {
+		if err := validV2(entries); err != nil {
+			return true
+		}
+
+		encoded, _ := encodeV2(entries)
+		lines := strings.Split(encoded, "\n")
+
+		_, err := decodeV2(lines[:len(lines)-1], 3)
+		return err == nil
+	}
+
+	if err := quick.Check(decodable, nil); err != nil {
+		t.Errorf("decode(encode(x)) errored for: %v", err)
+	}
+
+	deterministic := func(entries []Entry) bool {
+		got1, err1 := encodeV2(entries)
+		got2, err2 := encodeV2(entries)
+		return got1 == got2 && ((err1 == nil) == (err2 == nil))
+	}
+
+	if err := quick.Check(deterministic, nil); err != nil {
+		t.Errorf("encode(x) != encode(x) for: %v", err)
+	}
+}
+
+func TestDecodeV2(t *testing.T) {
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []string
+			want    []Entry
+		}
+
+		testCases := []TestCase{
+			{
+				name:    "no checksums",
+				content: []string{},
+				want:    []Entry{},
+			},
+			{
+				name: "one checksum",
+				content: []string{
+					"foo h1:bar",
+				},
+				want: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+					},
+				},
+			},
+			{
+				name: "one multi-part ID checksum",
+				content: []string{
+					"foo@bar h1:foobar",
+				},
+				want: []Entry{
+					{
+						Checksum: "h1:foobar",
+						ID:       []string{"foo", "bar"},
+					},
+				},
+			},
+			{
+				name: "attributes",
+				content: []string{
+					"foo h1:bar kind=action x-custom=a=b",
+				},
+				want: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+						Attributes: map[string]string{
+							"kind":     "action",
+							"x-custom": "a=b",
+						},
+					},
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := decodeV2(tc.content, 3)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v", err)
+				}
+
+				if got, want := len(got), len(tc.want); got != want {
+					t.Fatalf("Incorrect result length (got %d, want %d)", got, want)
+				}
+
+				for i, got := range got {
+					want := tc.want[i]
+
+					if got, want := got.Checksum, want.Checksum; got != want {
+						t.Fatalf("Incorrect checksum %d (got %q, want %q)", i, got, want)
+					}
+
+					if got, want := got.ID, want.ID; !slices.Equal(got, want) {
+						t.Fatalf("Incorrect id %d (got %v, want %v)", i, got, want)
+					}
+
+					if got, want := got.Attributes, want.Attributes; !maps.Equal(got, want) {
+						t.Fatalf("Incorrect attributes %d (got %v, want %v)", i, got, want)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []string
+			want    int
+		}
+
+		testCases := []TestCase{
+			{
+				name: "no id-checksum separator",
+				content: []string{
+					"foobar",
+				},
+				want: 3,
+			},
+			{
+				name: "no checksum",
+				content: []string{
+					"foobar ",
+				},
+				want: 3,
+			},
+			{
+				name: "no id",
+				content: []string{
+					" h1:foobar",
+				},
+				want: 3,
+			},
+			{
+				name: "double space",
+				content: []string{
+					"foo  h1:bar",
+				},
+				want: 3,
+			},
+			{
+				name: "attribute without value separator",
+				content: []string{
+					"foo h1:bar kind",
+				},
+				want: 3,
+			},
+			{
+				name: "duplicate attribute",
+				content: []string{
+					"foo h1:bar kind=action kind=image",
+				},
+				want: 3,
+			},
+			{
+				name: "on a later line",
+				content: []string{
+					"foo h1:bar",
+					"syntax-error",
+				},
+				want: 4,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := decodeV2(tc.content, 3)
+				if err == nil {
+					t.Fatal("Unexpected success")
+				}
+
+				if got, want := err[0].Line, tc.want; got != want {
+					t.Errorf("Incorrect line number (got %d, want %d)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Corrupted examples", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := map[string][]string{
+			"untagged checksum": {
+				"foo bar",
+			},
+			"invalid attribute name": {
+				"foo h1:bar Kind=action",
+			},
+			"empty attribute value": {
+				"foo h1:bar kind=",
+			},
+		}
+
+		for name, content := range testCases {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := decodeV2(content, 3); err == nil {
+					t.Fatal("Unexpected success")
+				}
+			})
+		}
+	})
+}
+
+func TestEncodeV2(t *testing.T) {
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []Entry
+			want    string
+		}
+
+		testCases := []TestCase{
+			{
+				name:    "no checksums",
+				content: []Entry{},
+				want:    ``,
+			},
+			{
+				name: "one checksum",
+				content: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+					},
+				},
+				want: `foo h1:bar
+`,
+			},
+			{
+				name: "one multi-part ID checksum",
+				content: []Entry{
+					{
+						Checksum: "h1:foobar",
+						ID:       []string{"foo", "bar"},
+					},
+				},
+				want: `foo@bar h1:foobar
+`,
+			},
+			{
+				name: "attributes",
+				content: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+						Attributes: map[string]string{
+							"kind": "action",
+							"host": "github.com",
+						},
+					},
+				},
+				want: `foo h1:bar host=github.com kind=action
+`,
+			},
+			{
+				name: "order",
+				content: []Entry{
+					{
+						Checksum: "h1:bb",
+						ID:       []string{"b"},
+					},
+					{
+						Checksum: "h1:aa",
+						ID:       []string{"a"},
+					},
+				},
+				want: `a h1:aa
+b h1:bb
+`,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				got, err := encodeV2(tc.content)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v", err)
+				}
+
+				if want := tc.want; got != want {
+					t.Fatalf("Incorrect result (got %q, want %q)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []Entry
+		}
+
+		testCases := []TestCase{
+			{
+				name: "untagged checksum",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "anything",
+					},
+				},
+			},
+			{
+				name: "checksum with space",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "h1:Hello world!",
+					},
+				},
+			},
+			{
+				name: "ID part with '@'",
+				content: []Entry{
+					{
+						ID:       []string{"foo@bar"},
+						Checksum: "h1:anything",
+					},
+				},
+			},
+			{
+				name: "attribute name with space",
+				content: []Entry{
+					{
+						ID:         []string{"anything"},
+						Checksum:   "h1:anything",
+						Attributes: map[string]string{"a b": "c"},
+					},
+				},
+			},
+			{
+				name: "attribute value with space",
+				content: []Entry{
+					{
+						ID:         []string{"anything"},
+						Checksum:   "h1:anything",
+						Attributes: map[string]string{"a": "b c"},
+					},
+				},
+			},
+			{
+				name: "attribute value with newline",
+				content: []Entry{
+					{
+						ID:         []string{"anything"},
+						Checksum:   "h1:anything",
+						Attributes: map[string]string{"a": "b\nc"},
+					},
+				},
+			},
+			{
+				name: "comments",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "h1:anything",
+						Comments: []string{"reviewed"},
+					},
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := encodeV2(tc.content); err == nil {
+					t.Fatal("Unexpected success")
+				}
+			})
+		}
+	})
+}
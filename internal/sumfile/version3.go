@@ -0,0 +1,112 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sumfile
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// commentMarker is the prefix of a comment line in a checksum file.
+const commentMarker = "#"
+
+func decodeV3(lines []string, first int) ([]Entry, ErrorList) {
+	var errs ErrorList
+
+	entries := make([]Entry, 0, len(lines))
+	numbers := make([]int, 0, len(lines))
+
+	var (
+		comments    []string
+		commentLine int
+	)
+
+	for i, line := range lines {
+		if text, ok := strings.CutPrefix(line, commentMarker); ok {
+			if comments == nil {
+				commentLine = first + i
+			}
+
+			comments = append(comments, strings.TrimPrefix(text, " "))
+			continue
+		}
+
+		entry, ok := decodeEntryV2(&errs, line, first+i)
+		if ok {
+			entry.Comments = comments
+			entries = append(entries, entry)
+			numbers = append(numbers, first+i)
+		}
+
+		comments = nil
+	}
+
+	if comments != nil {
+		errs.add(commentLine, 1, ErrSyntax, lines[commentLine-first], "comment not attached to an entry")
+	}
+
+	checkDuplicates(&errs, entries, numbers)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return entries, nil
+}
+
+func encodeV3(entries []Entry) (string, error) {
+	if err := validV3(entries); err != nil {
+		return "", errors.Join(ErrCorrupted, err)
+	}
+
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return encodeEntryV2(sorted[i]) < encodeEntryV2(sorted[j])
+	})
+
+	var sb strings.Builder
+	for _, entry := range sorted {
+		for _, comment := range entry.Comments {
+			sb.WriteString(commentMarker)
+			if comment != "" {
+				sb.WriteRune(' ')
+				sb.WriteString(comment)
+			}
+
+			sb.WriteRune('\n')
+		}
+
+		sb.WriteString(encodeEntryV2(entry))
+	}
+
+	return sb.String(), nil
+}
+
+func validV3(entries []Entry) error {
+	for _, entry := range entries {
+		for _, comment := range entry.Comments {
+			if strings.Contains(comment, "\n") {
+				return ErrSyntax
+			}
+		}
+
+		if strings.HasPrefix(strings.Join(entry.ID, "@"), commentMarker) {
+			return ErrSyntax
+		}
+	}
+
+	return validEntriesV2(entries)
+}
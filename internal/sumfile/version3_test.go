@@ -0,0 +1,322 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sumfile
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestVersion3(t *testing.T) {
+	t.Parallel()
+
+	correct := func(entries []Entry) bool {
+		if err := validV3(entries); err != nil {
+			return true
+		}
+
+		encoded, _ := encodeV3(entries)
+		lines := strings.Split(encoded, "\n")
+
+		decoded, err := decodeV3(lines[:len(lines)-1], 3)
+		if err != nil {
+			return true // Ignore errors, tested separately
+		}
+
+		return SetEqual(decoded, entries)
+	}
+
+	if err := quick.Check(correct, nil); err != nil {
+		t.Errorf("decode(encode(x)) != x for: %v", err)
+	}
+
+	decodable := func(entries []Entry) bool {
+		if err := validV3(entries); err != nil {
+			return true
+		}
+
+		encoded, _ := encodeV3(entries)
+		lines := strings.Split(encoded, "\n")
+
+		_, err := decodeV3(lines[:len(lines)-1], 3)
+		return err == nil
+	}
+
+	if err := quick.Check(decodable, nil); err != nil {
+		t.Errorf("decode(encode(x)) errored for: %v", err)
+	}
+
+	deterministic := func(entries []Entry) bool {
+		got1, err1 := encodeV3(entries)
+		got2, err2 := encodeV3(entries)
+		return got1 == got2 && ((err1 == nil) == (err2 == nil))
+	}
+
+	if err := quick.Check(deterministic, nil); err != nil {
+		t.Errorf("encode(x) != encode(x) for: %v", err)
+	}
+}
+
+func TestDecodeV3(t *testing.T) {
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []string
+			want    []Entry
+		}
+
+		testCases := []TestCase{
+			{
+				name:    "no checksums",
+				content: []string{},
+				want:    []Entry{},
+			},
+			{
+				name: "no comments",
+				content: []string{
+					"foo h1:bar kind=action",
+				},
+				want: []Entry{
+					{
+						Checksum:   "h1:bar",
+						ID:         []string{"foo"},
+						Attributes: map[string]string{"kind": "action"},
+					},
+				},
+			},
+			{
+				name: "one comment",
+				content: []string{
+					"# reviewed by security",
+					"foo h1:bar",
+				},
+				want: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+						Comments: []string{"reviewed by security"},
+					},
+				},
+			},
+			{
+				name: "multi-line comment",
+				content: []string{
+					"# reviewed by security",
+					"#",
+					"#ticket SEC-123",
+					"foo h1:bar",
+					"bar h1:foo",
+				},
+				want: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+						Comments: []string{"reviewed by security", "", "ticket SEC-123"},
+					},
+					{
+						Checksum: "h1:foo",
+						ID:       []string{"bar"},
+					},
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := decodeV3(tc.content, 3)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v", err)
+				}
+
+				if got, want := len(got), len(tc.want); got != want {
+					t.Fatalf("Incorrect result length (got %d, want %d)", got, want)
+				}
+
+				for i, got := range got {
+					if want := tc.want[i]; !EntryEqual(got, want) {
+						t.Fatalf("Incorrect entry %d (got %v, want %v)", i, got, want)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []string
+			want    int
+		}
+
+		testCases := []TestCase{
+			{
+				name: "syntax error after a comment",
+				content: []string{
+					"# a comment",
+					"syntax-error",
+				},
+				want: 4,
+			},
+			{
+				name: "dangling comment",
+				content: []string{
+					"foo h1:bar",
+					"# a comment",
+				},
+				want: 4,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := decodeV3(tc.content, 3)
+				if err == nil {
+					t.Fatal("Unexpected success")
+				}
+
+				if got, want := err[0].Line, tc.want; got != want {
+					t.Errorf("Incorrect line number (got %d, want %d)", got, want)
+				}
+			})
+		}
+	})
+}
+
+func TestEncodeV3(t *testing.T) {
+	t.Run("Valid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []Entry
+			want    string
+		}
+
+		testCases := []TestCase{
+			{
+				name:    "no checksums",
+				content: []Entry{},
+				want:    ``,
+			},
+			{
+				name: "comments",
+				content: []Entry{
+					{
+						Checksum: "h1:bar",
+						ID:       []string{"foo"},
+						Comments: []string{"reviewed by security", "", "ticket SEC-123"},
+					},
+				},
+				want: `# reviewed by security
+#
+# ticket SEC-123
+foo h1:bar
+`,
+			},
+			{
+				name: "order",
+				content: []Entry{
+					{
+						Checksum: "h1:bb",
+						ID:       []string{"b"},
+						Comments: []string{"about b"},
+					},
+					{
+						Checksum: "h1:aa",
+						ID:       []string{"a"},
+						Comments: []string{"about a"},
+					},
+				},
+				want: `# about a
+a h1:aa
+# about b
+b h1:bb
+`,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				got, err := encodeV3(tc.content)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v", err)
+				}
+
+				if want := tc.want; got != want {
+					t.Fatalf("Incorrect result (got %q, want %q)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		type TestCase struct {
+			name    string
+			content []Entry
+		}
+
+		testCases := []TestCase{
+			{
+				name: "untagged checksum",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "anything",
+					},
+				},
+			},
+			{
+				name: "comment with newline",
+				content: []Entry{
+					{
+						ID:       []string{"anything"},
+						Checksum: "h1:anything",
+						Comments: []string{"foo\nbar"},
+					},
+				},
+			},
+			{
+				name: "ID starting with the comment marker",
+				content: []Entry{
+					{
+						ID:       []string{"#anything"},
+						Checksum: "h1:anything",
+					},
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := encodeV3(tc.content); err == nil {
+					t.Fatal("Unexpected success")
+				}
+			})
+		}
+	})
+}
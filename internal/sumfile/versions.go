@@ -21,6 +21,18 @@ const (
 	// Version1 is the first checksum file version.
 	Version1 Version = 1 + iota
 
+	// Version2 is the second checksum file version. It requires every checksum
+	// to be tagged and supports attributes for every entry.
+	Version2
+
+	// Version3 is the third checksum file version. It extends Version2 with
+	// support for comments attached to entries.
+	Version3
+
+	// VersionDefault has the value of the checksum file Version used for new
+	// checksum files. Later versions are only used when migrated to explicitly.
+	VersionDefault = Version1
+
 	// VersionLatest has the value of the latest checksum file Version.
-	VersionLatest = Version1
+	VersionLatest = Version3
 )
@@ -0,0 +1,424 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is an in-memory file system hierarchy, it implements fs.FS.
+//
+// Symbolic links are followed when opening files, but only within the file
+// system. A symbolic link that points outside the file system is considered to
+// be broken.
+type FS struct {
+	nodes map[string]*node
+	mu    sync.RWMutex
+}
+
+// Opener is a function that opens the content of a file.
+type Opener func() (io.ReadCloser, error)
+
+type node struct {
+	children map[string]struct{}
+	open     Opener
+	target   string
+	data     []byte
+	size     int64
+	mode     fs.FileMode
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+type file struct {
+	info   fileInfo
+	open   Opener
+	reader io.ReadCloser
+	closed bool
+}
+
+type dir struct {
+	name    string
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+// maxLinks is the maximum number of symbolic links followed to resolve a path.
+const maxLinks = 40
+
+// New creates a new, empty, file system hierarchy.
+func New() *FS {
+	root := node{
+		children: make(map[string]struct{}, 0),
+		mode:     fs.ModeDir | 0o755,
+	}
+
+	return &FS{
+		nodes: map[string]*node{".": &root},
+	}
+}
+
+// MkdirAll creates the directory name, along with any necessary parents.
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.mkdirAll(name, perm)
+}
+
+// WriteFile creates the file name with the given content, along with any
+// necessary parent directories.
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	n := node{
+		data: data,
+		size: int64(len(data)),
+		mode: perm.Perm(),
+	}
+
+	return f.add("write", name, &n)
+}
+
+// WriteLazyFile creates the file name of the given size whose content is
+// obtained using open, along with any necessary parent directories. The
+// content is not read until the file is read.
+func (f *FS) WriteLazyFile(name string, size int64, open Opener, perm fs.FileMode) error {
+	n := node{
+		open: open,
+		size: size,
+		mode: perm.Perm(),
+	}
+
+	return f.add("write", name, &n)
+}
+
+// Symlink creates name as a symbolic link to target, along with any necessary
+// parent directories.
+func (f *FS) Symlink(target, name string) error {
+	n := node{
+		target: target,
+		size:   int64(len(target)),
+		mode:   fs.ModeSymlink | 0o777,
+	}
+
+	return f.add("symlink", name, &n)
+}
+
+// Open opens the named file, following symbolic links.
+func (f *FS) Open(name string) (fs.File, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, n, err := f.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	info := n.info(name)
+	if n.mode.IsDir() {
+		entries, _ := f.readDir(name)
+		return &dir{name: name, info: info, entries: entries}, nil
+	}
+
+	open := n.open
+	if open == nil {
+		data := n.data
+		open = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	return &file{info: info, open: open}, nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+// Symbolic links in the directory are not followed.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (f *FS) ReadLink(name string) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, n, err := f.resolve(name, false)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return n.target, nil
+}
+
+// Lstat returns a FileInfo describing the named file without following a
+// symbolic link at name.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, n, err := f.resolve(name, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+
+	return n.info(name), nil
+}
+
+// Stat returns a FileInfo describing the named file, following symbolic links.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, n, err := f.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return n.info(name), nil
+}
+
+func (f *FS) add(op, name string, n *node) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parent := path.Dir(name)
+	if err := f.mkdirAll(parent, 0o755); err != nil {
+		return err
+	}
+
+	if _, ok := f.nodes[name]; ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+
+	f.nodes[name] = n
+	f.nodes[parent].children[path.Base(name)] = struct{}{}
+
+	return nil
+}
+
+func (f *FS) mkdirAll(name string, perm fs.FileMode) error {
+	if name == "." {
+		return nil
+	}
+
+	if n, ok := f.nodes[name]; ok {
+		if !n.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+
+		return nil
+	}
+
+	parent := path.Dir(name)
+	if err := f.mkdirAll(parent, perm); err != nil {
+		return err
+	}
+
+	f.nodes[name] = &node{
+		children: make(map[string]struct{}, 0),
+		mode:     fs.ModeDir | perm.Perm(),
+	}
+	f.nodes[parent].children[path.Base(name)] = struct{}{}
+
+	return nil
+}
+
+func (f *FS) readDir(name string) ([]fs.DirEntry, error) {
+	resolved, n, err := f.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.mode.IsDir() {
+		return nil, errors.New("not a directory")
+	}
+
+	names := make([]string, 0, len(n.children))
+	for child := range n.children {
+		names = append(names, child)
+	}
+
+	slices.Sort(names)
+
+	entries := make([]fs.DirEntry, len(names))
+	for i, child := range names {
+		info := f.nodes[path.Join(resolved, child)].info(child)
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+
+	return entries, nil
+}
+
+func (f *FS) resolve(name string, followLast bool) (string, *node, error) {
+	if !fs.ValidPath(name) {
+		return "", nil, fs.ErrInvalid
+	}
+
+	var parts []string
+	if name != "." {
+		parts = strings.Split(name, "/")
+	}
+
+	current, links := ".", 0
+	for i := 0; i < len(parts); i++ {
+		next := path.Join(current, parts[i])
+		n, ok := f.nodes[next]
+		if !ok {
+			return "", nil, fs.ErrNotExist
+		}
+
+		last := i == len(parts)-1
+		if n.mode&fs.ModeSymlink != 0 && (!last || followLast) {
+			if links++; links > maxLinks {
+				return "", nil, errors.New("too many links")
+			}
+
+			target := path.Join(current, n.target)
+			if path.IsAbs(n.target) || target == ".." || strings.HasPrefix(target, "../") {
+				return "", nil, fs.ErrNotExist
+			}
+
+			remaining := parts[i+1:]
+			parts = nil
+			if target != "." {
+				parts = strings.Split(target, "/")
+			}
+
+			parts = append(parts, remaining...)
+			current, i = ".", -1
+			continue
+		}
+
+		if !last && !n.mode.IsDir() {
+			return "", nil, fs.ErrNotExist
+		}
+
+		current = next
+	}
+
+	return current, f.nodes[current], nil
+}
+
+func (n *node) info(name string) fileInfo {
+	return fileInfo{
+		name: path.Base(name),
+		size: n.size,
+		mode: n.mode,
+	}
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() any           { return nil }
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if f.reader == nil {
+		reader, err := f.open()
+		if err != nil {
+			return 0, err
+		}
+
+		f.reader = reader
+	}
+
+	return f.reader.Read(b)
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+
+	f.closed = true
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+
+	return nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+
+	d.offset += count
+	return remaining[:count], nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Eric Cornelissen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := New()
+	if err := fsys.WriteFile("foo.txt", []byte("foo"), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := fsys.WriteFile("bar/baz.txt", []byte("baz"), 0o755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	open := func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("lazy")), nil
+	}
+
+	if err := fsys.WriteLazyFile("bar/lazy.txt", 4, open, 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := fsys.MkdirAll("empty/dir", 0o755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := fstest.TestFS(fsys, "foo.txt", "bar/baz.txt", "bar/lazy.txt", "empty/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fs.ReadFile(fsys, "bar/lazy.txt"); err != nil || string(data) != "lazy" {
+		t.Errorf("Incorrect lazy file content (got %q, %v)", data, err)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	t.Parallel()
+
+	fsys := New()
+	if err := fsys.WriteFile("dir/file.txt", []byte("Hello world!"), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	links := map[string]string{
+		"link-to-file":        "dir/file.txt",
+		"link-to-dir":         "dir",
+		"dir/link-to-sibling": "file.txt",
+		"dir/link-to-parent":  "../link-to-file",
+		"link-to-link":        "link-to-file",
+		"link-to-outside":     "../outside",
+		"link-to-absolute":    "/etc/passwd",
+		"link-to-missing":     "missing",
+		"loop-a":              "loop-b",
+		"loop-b":              "loop-a",
+	}
+
+	for name, target := range links {
+		if err := fsys.Symlink(target, name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("Followed", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []string{
+			"link-to-file",
+			"link-to-dir/file.txt",
+			"dir/link-to-sibling",
+			"dir/link-to-parent",
+			"link-to-link",
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc, func(t *testing.T) {
+				t.Parallel()
+
+				data, err := fs.ReadFile(fsys, tc)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if got, want := string(data), "Hello world!"; got != want {
+					t.Errorf("Incorrect content (got %q, want %q)", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Broken", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []string{
+			"link-to-outside",
+			"link-to-absolute",
+			"link-to-missing",
+			"loop-a",
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc, func(t *testing.T) {
+				t.Parallel()
+
+				if _, err := fsys.Open(tc); err == nil {
+					t.Fatal("Unexpected success")
+				}
+			})
+		}
+	})
+
+	t.Run("ReadLink", func(t *testing.T) {
+		t.Parallel()
+
+		for name, want := range links {
+			got, err := fsys.ReadLink(name)
+			if err != nil {
+				t.Errorf("Unexpected error for %q: %v", name, err)
+			} else if got != want {
+				t.Errorf("Incorrect target for %q (got %q, want %q)", name, got, want)
+			}
+		}
+
+		if _, err := fsys.ReadLink("dir/file.txt"); err == nil {
+			t.Error("Unexpected success for a regular file")
+		}
+	})
+
+	t.Run("Lstat", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := fsys.Lstat("link-to-dir")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("Expected a symbolic link (got %v)", info.Mode())
+		}
+	})
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+
+	fsys := New()
+	if err := fsys.WriteFile("foo", []byte("foo"), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := fsys.WriteFile("foo", []byte("bar"), 0o644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected an fs.ErrExist error (got %v)", err)
+	}
+
+	if err := fsys.WriteFile("foo/bar", []byte("bar"), 0o644); err == nil {
+		t.Error("Unexpected success for a file in a file")
+	}
+}